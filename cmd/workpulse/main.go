@@ -1,56 +1,213 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"main/internal/domain"
+	focusx11 "main/internal/focus/x11"
+	"main/internal/idle/logind"
+	"main/internal/idle/x11"
+	"main/internal/input/gohook"
+	"main/internal/input/replay"
 	"main/internal/repository/sqlite"
+	"main/internal/repository/sqlite/migrations"
 	"main/internal/ui/window"
 	"main/internal/usecase"
+	"main/pkg/clock"
+	"os"
+	"path/filepath"
+	"time"
 
 	"fyne.io/fyne/v2/app"
 	_ "github.com/mattn/go-sqlite3"
-
-	hook "github.com/robotn/gohook"
 )
 
 func main() {
-	db, err := initDB()
+	replayPath := flag.String("replay", "", "從 JSON Lines 檔案重播輸入事件，取代全域輸入監聽")
+	replaySpeed := flag.Float64("replay-speed", 1, "重播倍速")
+	rebuildRollups := flag.Bool("rebuild-rollups", false, "從原始活動記錄重新產生彙總後結束")
+	exportAudit := flag.String("export-audit", "", "將所有稽核記錄匯出成 CSV 檔後結束")
+	applyRules := flag.Bool("apply-rules", false, "以規則檔重新歸類所有活動的專案後結束")
+	retentionDryRun := flag.Bool("retention-dry-run", false, "試執行保存政策並輸出報告後結束")
+	profileName := flag.String("profile", os.Getenv(usecase.ProfileEnv), "設定檔名稱，每個設定檔有獨立的設定與資料庫")
+	dbPath := flag.String("db", "", "資料庫位置，優先於環境變數 "+usecase.DatabaseEnv+" 與設定檔")
+	flag.Parse()
+
+	profile, err := usecase.NewProfile(*profileName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
+	log.Printf("使用設定檔: %s", profile.Name)
 
-	settings := usecase.NewSettingsManager()
+	settings := usecase.NewSettingsManager(profile)
 	if err := settings.LoadSettings(); err != nil {
 		log.Printf("載入設定時發生錯誤: %v", err)
 	}
 
-	repo := sqlite.NewSQLiteActivityRepository(db)
-	tracker := usecase.NewActivityTracker(repo)
+	path := profile.ResolveDatabasePath(*dbPath, settings.GetSettings())
+	if path == profile.DefaultDatabasePath() {
+		// 舊版把資料庫放在工作目錄，升級後沿用原本的記錄
+		if path, err = profile.AdoptLegacyDatabase(); err != nil {
+			log.Printf("沿用舊的資料庫時發生錯誤: %v", err)
+		}
+	}
+
+	db, err := initDB(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	clk := clock.New()
+	repo := sqlite.NewSQLiteActivityRepository(db, clk)
+	tracker := usecase.NewActivityTracker(repo, sqlite.NewSQLiteStatsRepository(db), clk)
 	tracker.UpdateThreshold(settings.GetSettings().ThresholdSeconds)
+	tracker.UpdateTypeThreshold(domain.MouseActivity, settings.GetSettings().MouseThresholdSeconds)
+	tracker.UpdateTypeThreshold(domain.KeyboardActivity, settings.GetSettings().KeyboardThresholdSeconds)
+	tracker.UpdateSessionGap(settings.GetSettings().SessionGapSeconds)
+	tracker.UpdateHideWindowTitles(settings.GetSettings().HideWindowTitles)
+
+	cal, err := settings.GetSettings().Calendar()
+	if err != nil {
+		log.Printf("載入時區設定時發生錯誤，改用系統時區: %v", err)
+	}
+	if err := tracker.UpdateCalendar(cal); err != nil {
+		log.Printf("更新每日邊界時發生錯誤: %v", err)
+	}
 
-	// 清理未完成的活動
-	if err := tracker.CleanupUnfinishedActivities(); err != nil {
-		log.Printf("清理未完成活動時發生錯誤: %v", err)
+	// 依規則檔將焦點時的活動歸類到專案，規則檔有錯誤時不歸類
+	projectRepo := sqlite.NewSQLiteProjectRepository(db, clk)
+	rules := usecase.NewRuleEngine(profile, projectRepo)
+	if err := rules.Load(context.Background()); err != nil {
+		log.Printf("載入規則檔時發生錯誤: %v", err)
 	}
+	tracker.UpdateRules(rules)
 
-	// 啟動閾值檢查器
-	tracker.StartThresholdChecker()
+	// 沒有符合規則的新活動屬於上次選擇的專案，手動編輯與切換專案共用同一個復原歷程
+	editor := usecase.NewActivityEditor(repo, projectRepo, tracker, clk)
+	projects := usecase.NewProjectService(projectRepo, tracker, settings, editor)
+	if err := projects.LoadCurrentProject(context.Background()); err != nil {
+		log.Printf("設定目前的專案時發生錯誤: %v", err)
+	}
+
+	if *applyRules {
+		changed, err := tracker.ApplyRules(context.Background())
+		if err != nil {
+			log.Fatalf("套用規則時發生錯誤: %v", err)
+		}
+		log.Printf("已重新歸類 %d 筆活動", changed)
+		return
+	}
+
+	audit := usecase.NewAuditService(sqlite.NewSQLiteAuditRepository(db))
+	if *exportAudit != "" {
+		if err := exportAuditLog(audit, *exportAudit, clk, cal); err != nil {
+			log.Fatalf("匯出稽核記錄時發生錯誤: %v", err)
+		}
+		return
+	}
+
+	if *rebuildRollups {
+		if err := repo.RebuildRollups(context.Background()); err != nil {
+			log.Fatalf("重新產生彙總時發生錯誤: %v", err)
+		}
+		return
+	}
+
+	retention := usecase.NewRetentionService(repo, settings, tracker, clk)
+	if *retentionDryRun {
+		report, err := retention.Apply(context.Background(), true)
+		if err != nil {
+			log.Fatalf("試執行保存政策時發生錯誤: %v", err)
+		}
+		if report.Plan.CompactBefore.IsZero() && report.Plan.PurgeBefore.IsZero() {
+			log.Printf("保存政策未啟用")
+		}
+		return
+	}
+
+	// 復原上次未完成的活動
+	if err := tracker.RecoverUnfinishedActivities(); err != nil {
+		log.Printf("復原未完成活動時發生錯誤: %v", err)
+	}
+
+	// 啟動閾值檢查器，視窗關閉後結束進行中的活動
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := tracker.Run(ctx); err != nil {
+			log.Printf("活動追蹤器停止: %v", err)
+		}
+	}()
+	defer func() {
+		if err := tracker.Close(); err != nil {
+			log.Printf("關閉活動追蹤器時發生錯誤: %v", err)
+		}
+	}()
+
+	// 只追蹤設定允許的輸入事件種類
+	privacy := usecase.NewPrivacyFilter(settings.GetSettings().TrackedInputKinds)
+
+	// 每分鐘寫入輸入強度，結束前寫入剩下的計數
+	intensity := usecase.NewIntensityRecorder(sqlite.NewSQLiteIntensityRepository(db), clk)
+	go func() {
+		if err := intensity.Run(ctx); err != nil {
+			log.Printf("輸入強度記錄停止: %v", err)
+		}
+	}()
+	defer func() {
+		if err := intensity.Flush(context.Background()); err != nil {
+			log.Printf("寫入輸入強度時發生錯誤: %v", err)
+		}
+	}()
+
+	// 定期執行保存政策
+	go func() {
+		if err := retention.Run(ctx); err != nil {
+			log.Printf("保存政策停止: %v", err)
+		}
+	}()
 
 	// 啟動監聽程序
-	go trackActivity(tracker)
+	go func() {
+		if err := tracker.Track(ctx, intensity.Wrap(privacy.Wrap(newInputSource(clk, *replayPath, *replaySpeed)))); err != nil {
+			log.Printf("監聽輸入事件時發生錯誤: %v", err)
+		}
+	}()
+
+	// 監聽作業系統的閒置、螢幕鎖定與休眠事件，無法使用的來源只記錄後略過
+	for _, source := range newIdleSources(clk, settings.GetSettings()) {
+		go func() {
+			if err := tracker.Watch(ctx, source); err != nil {
+				log.Printf("監聽系統事件時發生錯誤: %v", err)
+			}
+		}()
+	}
+
+	// 記錄焦點所在的應用程式與視窗標題
+	if os.Getenv("DISPLAY") != "" {
+		go func() {
+			if err := tracker.TrackFocus(ctx, focusx11.New(clk)); err != nil {
+				log.Printf("監聽焦點視窗時發生錯誤: %v", err)
+			}
+		}()
+	}
 
 	myApp := app.New()
-	mainWindow := window.NewMainWindow(myApp, tracker, settings)
+	mainWindow := window.NewMainWindow(myApp, tracker, intensity, privacy, rules, projects, editor, audit, settings, clk)
 	mainWindow.Show()
 }
 
-func initDB() (*sql.DB, error) {
-	dbPath := "workpulse.db"
+func initDB(dbPath string) (*sql.DB, error) {
 	log.Printf("正在連接資料庫: %s", dbPath)
 
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, fmt.Errorf("無法建立資料庫目錄: %v", err)
+	}
+
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("無法開啟資料庫: %v", err)
@@ -63,40 +220,45 @@ func initDB() (*sql.DB, error) {
 
 	log.Printf("資料庫連接成功")
 
-	// 創建活動記錄表
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS activities (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			start_time INTEGER NOT NULL,  -- Unix timestamp in seconds
-			end_time INTEGER,            -- Unix timestamp in seconds, NULL means not ended
-			activity_type TEXT NOT NULL
-		)
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("無法創建資料表: %v", err)
+	// 執行資料庫遷移
+	if err := migrations.Migrate(context.Background(), db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("無法遷移資料庫: %v", err)
 	}
 
-	log.Printf("資料表檢查/創建完成")
+	log.Printf("資料庫遷移完成")
 	return db, nil
 }
 
-func trackActivity(tracker *usecase.ActivityTracker) {
-	evChan := hook.Start()
-	defer hook.End()
+func exportAuditLog(audit *usecase.AuditService, path string, clk clock.Clock, cal domain.Calendar) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("無法建立匯出檔: %v", err)
+	}
 
-	for ev := range evChan {
-		switch ev.Kind {
-		case hook.MouseMove, hook.MouseDrag, hook.MouseDown, hook.MouseUp,
-			hook.KeyDown, hook.KeyUp:
+	err = audit.ExportCSV(context.Background(), file, time.Unix(0, 0), clk.Now().Add(time.Second), cal.In(time.Time{}).Location())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("已匯出稽核記錄: %s", path)
+	return nil
+}
 
-			activityType := domain.MouseActivity
-			if ev.Kind == hook.KeyDown || ev.Kind == hook.KeyUp {
-				activityType = domain.KeyboardActivity
-			}
+func newInputSource(clk clock.Clock, replayPath string, replaySpeed float64) usecase.InputSource {
+	if replayPath != "" {
+		log.Printf("使用重播輸入來源: %s", replayPath)
+		return replay.New(clk, replayPath, replaySpeed)
+	}
+	return gohook.New(clk)
+}
 
-			if err := tracker.StartActivity(activityType); err != nil {
-				log.Printf("開始活動時發生錯誤: %v", err)
-			}
-		}
+func newIdleSources(clk clock.Clock, settings domain.Settings) []usecase.IdleSource {
+	sources := []usecase.IdleSource{logind.New()}
+	if os.Getenv("DISPLAY") != "" {
+		sources = append(sources, x11.New(clk, time.Duration(settings.ThresholdSeconds)*time.Second))
 	}
+	return sources
 }
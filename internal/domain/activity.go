@@ -7,6 +7,7 @@ type ActivityType string
 const (
 	MouseActivity    ActivityType = "mouse"
 	KeyboardActivity ActivityType = "keyboard"
+	ManualActivity   ActivityType = "manual" // 手動輸入的時間，例如離開電腦的會議
 )
 
 type Activity struct {
@@ -14,6 +15,8 @@ type Activity struct {
 	StartTimeUnix int64 // Unix timestamp in seconds
 	EndTimeUnix   int64 // Unix timestamp in seconds, 0 means not ended
 	Type          ActivityType
+	ProjectID     int64 // 所屬專案，0 表示沒有專案
+	Manual        bool  // 手動新增或修改過，報表可據此區分追蹤與輸入的時間
 }
 
 // 添加輔助方法來處理時間轉換
@@ -43,3 +46,15 @@ func (a *Activity) SetEndTime(t time.Time) {
 func (a *Activity) IsEnded() bool {
 	return a.EndTimeUnix > 0
 }
+
+// Overlaps 回傳兩筆活動是否有長度大於零的共同時間，進行中的活動算到 now 為止
+func (a *Activity) Overlaps(other Activity, now time.Time) bool {
+	aEnd, otherEnd := a.EndTimeUnix, other.EndTimeUnix
+	if !a.IsEnded() {
+		aEnd = now.Unix()
+	}
+	if !other.IsEnded() {
+		otherEnd = now.Unix()
+	}
+	return max(a.StartTimeUnix, other.StartTimeUnix) < min(aEnd, otherEnd)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// AuditOperation 是稽核記錄中的變更種類
+type AuditOperation string
+
+const (
+	AuditInsert AuditOperation = "insert"
+	AuditUpdate AuditOperation = "update"
+	AuditDelete AuditOperation = "delete"
+	AuditBulk   AuditOperation = "bulk" // 保存政策一次記錄整次執行的所有變更
+)
+
+// AuditSource 是造成變更的來源
+type AuditSource string
+
+const (
+	AuditTracker   AuditSource = "tracker"   // 輸入事件自動記錄
+	AuditManual    AuditSource = "manual"    // 使用者手動新增或修改
+	AuditRecovery  AuditSource = "recovery"  // 啟動時復原未結束的活動
+	AuditRetention AuditSource = "retention" // 保存政策
+	AuditUndo      AuditSource = "undo"      // 復原或重做使用者的變更
+	AuditRules     AuditSource = "rules"     // 依規則重新歸類專案
+)
+
+// AuditEntry 是一筆活動變更的稽核記錄，寫入後不可修改或刪除
+type AuditEntry struct {
+	ID         int64
+	Time       time.Time
+	Source     AuditSource
+	Operation  AuditOperation
+	ActivityID int64     // 0 表示批次操作
+	Before     *Activity // 變更前的活動，新增時為 nil
+	After      *Activity // 變更後的活動，刪除時為 nil
+	Tags       []int64   // 刪除時活動的標籤，從稽核記錄還原時一併加回
+	Detail     string
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Calendar 決定「一天」的範圍：以 Location 的時區計算，並從 DayStartHour 點開始，
+// 例如 DayStartHour=4 時，凌晨三點的活動仍算在前一天。
+// 每天的長度以日曆計算，遇到夏令時間切換時可能是 23 或 25 小時。
+type Calendar struct {
+	Location     *time.Location
+	DayStartHour int
+}
+
+func NewCalendar(loc *time.Location, dayStartHour int) Calendar {
+	if loc == nil {
+		loc = time.Local
+	}
+	if dayStartHour < 0 || dayStartHour > 23 {
+		dayStartHour = 0
+	}
+	return Calendar{Location: loc, DayStartHour: dayStartHour}
+}
+
+func (c Calendar) location() *time.Location {
+	if c.Location == nil {
+		return time.Local
+	}
+	return c.Location
+}
+
+// In 回傳 t 在日曆時區的時間
+func (c Calendar) In(t time.Time) time.Time {
+	return t.In(c.location())
+}
+
+// DayStart 回傳 t 所屬那一天的開始時間
+func (c Calendar) DayStart(t time.Time) time.Time {
+	local := t.In(c.location())
+	start := c.at(local.Year(), local.Month(), local.Day())
+	if local.Before(start) {
+		start = c.at(local.Year(), local.Month(), local.Day()-1)
+	}
+	return start
+}
+
+// NextDayStart 回傳 dayStart 的下一天開始時間
+func (c Calendar) NextDayStart(dayStart time.Time) time.Time {
+	local := dayStart.In(c.location())
+	return c.at(local.Year(), local.Month(), local.Day()+1)
+}
+
+// DayRange 回傳 t 所屬那一天的 [start, end) 範圍
+func (c Calendar) DayRange(t time.Time) (time.Time, time.Time) {
+	start := c.DayStart(t)
+	return start, c.NextDayStart(start)
+}
+
+func (c Calendar) at(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, c.DayStartHour, 0, 0, 0, c.location())
+}
+
+// DaySlice 是一段時間落在某一天之內的部分
+type DaySlice struct {
+	DayStart time.Time
+	Start    time.Time
+	End      time.Time
+}
+
+func (s DaySlice) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Split 依照每日邊界切分 [start, end)，跨日的活動會依比例分配到每一天。
+// 統計、報表與時間軸都應該透過它處理跨日，而不是各自計算。
+func (c Calendar) Split(start, end time.Time) []DaySlice {
+	if !end.After(start) {
+		return nil
+	}
+
+	var slices []DaySlice
+	dayStart := c.DayStart(start)
+	for dayStart.Before(end) {
+		dayEnd := c.NextDayStart(dayStart)
+
+		slice := DaySlice{DayStart: dayStart, Start: start, End: end}
+		if slice.Start.Before(dayStart) {
+			slice.Start = dayStart
+		}
+		if slice.End.After(dayEnd) {
+			slice.End = dayEnd
+		}
+		slices = append(slices, slice)
+
+		dayStart = dayEnd
+	}
+	return slices
+}
+
+// String 回傳可用來比較兩個 Calendar 是否切分出相同每日邊界的描述。
+// 系統時區的名稱一律是 "Local"，改用它實際對應的時區，系統時區改變後描述也會不同。
+func (c Calendar) String() string {
+	return fmt.Sprintf("%s@%02d", locationKey(c.location()), c.DayStartHour)
+}
+
+// locationKey 回傳時區的名稱，系統時區依照 time.Local 相同的規則找出 IANA 名稱：
+// 先看 TZ 環境變數，沒有設定時看 /etc/localtime 連結到的時區檔。
+// 找不到名稱時（例如 Windows）以今年冬夏兩季的時區縮寫與偏移描述。
+func locationKey(loc *time.Location) string {
+	if loc != time.Local {
+		return loc.String()
+	}
+
+	if tz, ok := os.LookupEnv("TZ"); ok {
+		tz = strings.TrimPrefix(tz, ":")
+		if tz == "" {
+			return "UTC"
+		}
+		return zoneinfoName(tz)
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		return zoneinfoName(target)
+	}
+
+	year := time.Now().Year()
+	winter, winterOffset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	summer, summerOffset := time.Date(year, time.July, 1, 0, 0, 0, 0, loc).Zone()
+	return fmt.Sprintf("Local(%s%+d,%s%+d)", winter, winterOffset, summer, summerOffset)
+}
+
+// zoneinfoName 取出時區檔路徑中 zoneinfo 目錄之後的部分，例如
+// "/usr/share/zoneinfo/Asia/Taipei" 是 "Asia/Taipei"，不是路徑時原樣回傳
+func zoneinfoName(path string) string {
+	if _, name, ok := strings.Cut(path, "zoneinfo/"); ok {
+		return name
+	}
+	return path
+}
@@ -0,0 +1,99 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"main/internal/domain"
+)
+
+// TestCalendarDaylightSavingDays 確認夏令時間開始的那一天是 23 小時、結束的那一天是 25 小時，
+// 跨過這兩天的活動切分後每一天的時間加總仍等於活動的長度
+func TestCalendarDaylightSavingDays(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := domain.NewCalendar(newYork, 0)
+	date := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, newYork)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		day        time.Time
+		length     time.Duration
+		start, end time.Time
+		slices     []time.Duration
+	}{
+		{
+			name:   "夏令時間開始",
+			day:    date(time.March, 8, 12),
+			length: 23 * time.Hour,
+			start:  date(time.March, 7, 22),
+			end:    date(time.March, 9, 2),
+			slices: []time.Duration{2 * time.Hour, 23 * time.Hour, 2 * time.Hour},
+		},
+		{
+			name:   "夏令時間結束",
+			day:    date(time.November, 1, 12),
+			length: 25 * time.Hour,
+			start:  date(time.October, 31, 23),
+			end:    date(time.November, 2, 1),
+			slices: []time.Duration{time.Hour, 25 * time.Hour, time.Hour},
+		},
+	} {
+		start, end := cal.DayRange(tc.day)
+		if want := date(tc.day.Month(), tc.day.Day(), 0); !start.Equal(want) {
+			t.Errorf("%s: 當天開始 = %v，應為 %v", tc.name, start, want)
+		}
+		if length := end.Sub(start); length != tc.length {
+			t.Errorf("%s: 當天長度 = %v，應為 %v", tc.name, length, tc.length)
+		}
+		if next := cal.NextDayStart(start); !next.Equal(end) {
+			t.Errorf("%s: 下一天開始 = %v，應為 %v", tc.name, next, end)
+		}
+
+		slices := cal.Split(tc.start, tc.end)
+		if len(slices) != len(tc.slices) {
+			t.Fatalf("%s: 切分成 %d 天，應為 %d 天: %+v", tc.name, len(slices), len(tc.slices), slices)
+		}
+		var total time.Duration
+		for i, slice := range slices {
+			if slice.Duration() != tc.slices[i] {
+				t.Errorf("%s: 第 %d 天 %v 的時間 = %v，應為 %v", tc.name, i+1, slice.DayStart, slice.Duration(), tc.slices[i])
+			}
+			total += slice.Duration()
+		}
+		if want := tc.end.Sub(tc.start); total != want {
+			t.Errorf("%s: 切分後的總時間 = %v，應為 %v", tc.name, total, want)
+		}
+	}
+}
+
+// TestCalendarStringResolvesLocalZone 確認系統時區以實際對應的時區描述，系統時區改變時描述也不同
+func TestCalendarStringResolvesLocalZone(t *testing.T) {
+	for _, tc := range []struct {
+		tz   string
+		want string
+	}{
+		{"America/New_York", "America/New_York@04"},
+		{":Asia/Taipei", "Asia/Taipei@04"},
+		{"/usr/share/zoneinfo/Europe/Berlin", "Europe/Berlin@04"},
+		{"", "UTC@04"},
+	} {
+		t.Setenv("TZ", tc.tz)
+		if got := domain.NewCalendar(time.Local, 4).String(); got != tc.want {
+			t.Errorf("TZ=%q: 每日邊界 = %q，應為 %q", tc.tz, got, tc.want)
+		}
+	}
+
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := domain.NewCalendar(taipei, 0).String(); got != "Asia/Taipei@00" {
+		t.Errorf("指定時區的每日邊界 = %q，應為 %q", got, "Asia/Taipei@00")
+	}
+}
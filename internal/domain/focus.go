@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// Focus 是取得焦點的應用程式與視窗標題
+type Focus struct {
+	App   string // 應用程式名稱，例如 X11 的 WM_CLASS
+	Title string // 視窗標題，設定不記錄標題時為空字串
+}
+
+// FocusEvent 是焦點來源回報的焦點改變
+type FocusEvent struct {
+	Focus
+	Time time.Time
+}
+
+// FocusPeriod 是同一個視窗持續取得焦點的時段，End 為零值表示仍在使用中
+type FocusPeriod struct {
+	ID int64
+	Focus
+	Start time.Time
+	End   time.Time
+}
+
+// AppStats 是一個應用程式在取得焦點時的活動時間
+type AppStats struct {
+	App      string
+	Duration time.Duration
+}
+
+// ProjectStats 是屬於一個專案的活動時間，ProjectID 為 0 表示沒有專案
+type ProjectStats struct {
+	ProjectID int64
+	Duration  time.Duration
+}
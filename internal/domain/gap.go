@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// GapReason 是沒有記錄活動的原因
+type GapReason string
+
+const (
+	GapSuspend   GapReason = "suspend"    // 系統回報的休眠
+	GapClockJump GapReason = "clock_jump" // 牆上時鐘與單調時鐘不一致，推測為休眠
+)
+
+// Gap 是電腦休眠等無法記錄活動的時段，讓報表可以和閒置區分
+type Gap struct {
+	ID     int64
+	Start  time.Time
+	End    time.Time
+	Reason GapReason
+}
+
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// InputKind 是輸入事件的種類，只用於計算輸入強度，不記錄按下的是哪個按鍵
+type InputKind string
+
+const (
+	InputOther    InputKind = ""
+	InputKeyPress InputKind = "key_press"
+	InputClick    InputKind = "click"
+	InputScroll   InputKind = "scroll"
+	InputMove     InputKind = "move"
+)
+
+// InputEvent 是輸入來源正規化後的事件，只保留活動類型、事件種類、發生時間與滑鼠移動距離。
+// 這是輸入來源與其他部分之間唯一的事件型別，刻意沒有任何可以放按鍵代碼或字元的欄位，
+// 新增欄位時不可加入能辨識按下哪個按鍵的資訊。
+type InputEvent struct {
+	Type     ActivityType
+	Time     time.Time
+	Kind     InputKind
+	Distance float64 // 滑鼠移動的距離（像素），只在 Kind 為 InputMove 時有值
+}
+
+// InputKinds 是所有可追蹤的事件種類
+var InputKinds = []InputKind{InputKeyPress, InputClick, InputScroll, InputMove}
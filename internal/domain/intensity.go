@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// InputIntensity 是一分鐘內的按鍵、點擊、捲動次數與滑鼠移動距離
+type InputIntensity struct {
+	Minute        time.Time
+	KeyPresses    int
+	Clicks        int
+	Scrolls       int
+	MouseDistance float64 // 像素
+}
+
+// Add 將一個輸入事件計入這一分鐘
+func (i *InputIntensity) Add(ev InputEvent) {
+	switch ev.Kind {
+	case InputKeyPress:
+		i.KeyPresses++
+	case InputClick:
+		i.Clicks++
+	case InputScroll:
+		i.Scrolls++
+	case InputMove:
+		i.MouseDistance += ev.Distance
+	}
+}
+
+// Merge 將同一分鐘的另一筆計數加入
+func (i *InputIntensity) Merge(other InputIntensity) {
+	i.KeyPresses += other.KeyPresses
+	i.Clicks += other.Clicks
+	i.Scrolls += other.Scrolls
+	i.MouseDistance += other.MouseDistance
+}
+
+func (i InputIntensity) IsZero() bool {
+	return i.KeyPresses == 0 && i.Clicks == 0 && i.Scrolls == 0 && i.MouseDistance == 0
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Project 是活動所屬的專案，供計費與工時表使用
+type Project struct {
+	ID   int64
+	Name string
+}
+
+// Tag 是附加在活動上的標籤，一筆活動可以有多個標籤
+type Tag struct {
+	ID   int64
+	Name string
+}
+
+// ProjectSegment 是活動從 Start 到下一段開始之前屬於 ProjectID 的部分，0 表示沒有專案
+type ProjectSegment struct {
+	Start     time.Time
+	ProjectID int64
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// RecoverySource 說明復原的活動結束時間從何而來
+type RecoverySource string
+
+const (
+	RecoveryFromHeartbeat RecoverySource = "heartbeat"
+	RecoveryFromThreshold RecoverySource = "threshold"
+)
+
+type RecoveredActivity struct {
+	Activity Activity
+	Source   RecoverySource
+}
+
+// RecoveryReport 記錄啟動時補上結束時間的未完成活動
+type RecoveryReport struct {
+	Heartbeat time.Time // 上次執行最後寫入的心跳時間，zero 表示沒有心跳
+	Recovered []RecoveredActivity
+	Focus     int // 補上結束時間的焦點時段數量
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "time"
+
+// RetentionPolicy 決定舊活動的保存方式，各欄位為 0 時表示不執行該步驟
+type RetentionPolicy struct {
+	CompactAfterDays int // 超過天數的活動將同一個工作階段內的同類型同專案記錄合併成一筆，記錄之間的短暫間隔會算入合併後的記錄
+	PurgeAfterMonths int // 超過月數的活動與彙總直接刪除
+}
+
+// RetentionPlan 是依照 RetentionPolicy 在某個時間點算出的實際界線
+type RetentionPlan struct {
+	CompactBefore time.Time     // zero 表示不合併
+	MergeGap      time.Duration // 合併時相鄰記錄之間允許的最大間隔，與工作階段間隔相同
+	PurgeBefore   time.Time     // zero 表示不刪除
+}
+
+type RetentionReport struct {
+	DryRun           bool
+	Plan             RetentionPlan
+	ScannedRows      int // 檢查是否合併的記錄數
+	MergedRows       int // 被合併進其他記錄而刪除的記錄數
+	Sessions         int // 合併後產生的記錄數
+	PurgedActivities int
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rule 依焦點的應用程式、視窗標題與時間將活動歸類到專案。
+// 所有條件都符合才算符合，空白的條件不限制；From 與 To 需同時設定。
+type Rule struct {
+	Project  string         // 專案名稱，不存在時自動建立
+	App      string         // 應用程式名稱，不分大小寫
+	Title    string         // 視窗標題的正規表示式
+	From     string         // 開始時間，例如 "09:00"
+	To       string         // 結束時間（不含），早於 From 表示跨過午夜
+	Weekdays []time.Weekday // 星期幾，0 是星期日
+}
+
+// ParseClock 解析 "15:04" 格式的時間，回傳從午夜起算的分鐘數
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("時間格式錯誤 %q，應為 HH:MM: %v", s, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
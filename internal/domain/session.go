@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// Session 是把間隔很短的活動記錄（不分類型）合併後的連續工作時段
+type Session struct {
+	Start            time.Time
+	End              time.Time
+	MouseDuration    time.Duration // 滑鼠記錄的時間合計
+	KeyboardDuration time.Duration // 鍵盤記錄的時間合計
+	ManualDuration   time.Duration // 手動輸入的時間合計
+	Segments         []Activity
+	InProgress       bool
+}
+
+// Duration 回傳工作階段從開始到結束的時間，包含其中的短暫間隔
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
@@ -1,5 +1,45 @@
 package domain
 
+import "time"
+
 type Settings struct {
-	ThresholdSeconds int
-} 
\ No newline at end of file
+	ThresholdSeconds         int
+	MouseThresholdSeconds    int         // 滑鼠的閒置閾值，0 表示使用 ThresholdSeconds
+	KeyboardThresholdSeconds int         // 鍵盤的閒置閾值，0 表示使用 ThresholdSeconds
+	Timezone                 string      // IANA 時區名稱，例如 "Asia/Taipei"，空字串表示使用系統時區
+	DayStartHour             int         // 一天從幾點開始（0-23）
+	DatabasePath             string      // 資料庫位置，空字串表示使用設定檔的預設位置
+	SessionGapSeconds        int         // 間隔小於此秒數的活動會合併成同一個工作階段
+	TrackedInputKinds        []InputKind // 追蹤的輸入事件種類，nil 表示全部追蹤，空的清單表示全部不追蹤
+	HideWindowTitles         bool        // 只記錄取得焦點的應用程式，不記錄視窗標題
+	CurrentProjectID         int64       // 新活動所屬的專案，0 表示沒有專案
+	Retention                RetentionPolicy
+}
+
+// ThresholdFor 回傳 activityType 的閒置閾值（秒）
+func (s Settings) ThresholdFor(activityType ActivityType) int {
+	switch activityType {
+	case MouseActivity:
+		if s.MouseThresholdSeconds > 0 {
+			return s.MouseThresholdSeconds
+		}
+	case KeyboardActivity:
+		if s.KeyboardThresholdSeconds > 0 {
+			return s.KeyboardThresholdSeconds
+		}
+	}
+	return s.ThresholdSeconds
+}
+
+// Calendar 依照設定的時區與每日開始時間建立 Calendar
+func (s Settings) Calendar() (Calendar, error) {
+	loc := time.Local
+	if s.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(s.Timezone)
+		if err != nil {
+			return NewCalendar(time.Local, s.DayStartHour), err
+		}
+	}
+	return NewCalendar(loc, s.DayStartHour), nil
+}
@@ -7,4 +7,16 @@ type DailyStats struct {
 	TotalDuration    time.Duration
 	MouseDuration    time.Duration
 	KeyboardDuration time.Duration
-} 
\ No newline at end of file
+	ManualDuration   time.Duration  // 手動輸入的時間
+	Apps             []AppStats     // 各應用程式的活動時間，時間最長的在前
+	Projects         []ProjectStats // 各專案的活動時間，時間最長的在前
+}
+
+// HourlyStats 是每小時的活動時間，Hour 為該小時的開始時間
+type HourlyStats struct {
+	Hour             time.Time
+	TotalDuration    time.Duration
+	MouseDuration    time.Duration
+	KeyboardDuration time.Duration
+	ManualDuration   time.Duration
+}
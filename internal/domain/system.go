@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// SystemEventKind 是作業系統回報的閒置、鎖定與休眠狀態變化
+type SystemEventKind string
+
+const (
+	SystemIdle    SystemEventKind = "idle"    // 使用者閒置
+	SystemActive  SystemEventKind = "active"  // 使用者從閒置恢復
+	SystemLock    SystemEventKind = "lock"    // 螢幕鎖定
+	SystemUnlock  SystemEventKind = "unlock"  // 螢幕解鎖
+	SystemSuspend SystemEventKind = "suspend" // 即將休眠
+	SystemResume  SystemEventKind = "resume"  // 從休眠喚醒
+)
+
+// SystemEvent 是閒置來源正規化後的事件，Time 為狀態實際改變的時間，
+// 例如閒置事件的 Time 是最後一次輸入的時間，而不是偵測到閒置的時間
+type SystemEvent struct {
+	Kind SystemEventKind
+	Time time.Time
+}
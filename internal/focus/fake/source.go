@@ -0,0 +1,52 @@
+package fake
+
+import (
+	"context"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+)
+
+var _ usecase.FocusSource = &Source{}
+
+// Source 是手動送出焦點改變的焦點來源，用於測試與展示
+type Source struct {
+	events chan domain.FocusEvent
+}
+
+func New() *Source {
+	return &Source{events: make(chan domain.FocusEvent)}
+}
+
+// Send 送出一個焦點改變，直到接收端收到或 ctx 結束
+func (s *Source) Send(ctx context.Context, ev domain.FocusEvent) error {
+	select {
+	case s.events <- ev:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.FocusEvent, error) {
+	events := make(chan domain.FocusEvent)
+
+	go func() {
+		defer close(events)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-s.events:
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
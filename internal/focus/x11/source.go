@@ -0,0 +1,168 @@
+//go:build linux && cgo
+
+package x11
+
+/*
+#cgo LDFLAGS: -lX11
+#include <stdlib.h>
+#include <string.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <X11/Xutil.h>
+
+// 視窗可能在查詢途中關閉，忽略錯誤以免 Xlib 預設的處理結束整個程式
+static int ignore_error(Display *display, XErrorEvent *event) {
+	return 0;
+}
+
+static void install_error_handler(void) {
+	XSetErrorHandler(ignore_error);
+}
+
+// window_property 讀取視窗屬性，呼叫端需以 XFree 釋放回傳的資料
+static unsigned char *window_property(Display *display, Window window, const char *name, Atom type, unsigned long *count) {
+	Atom property = XInternAtom(display, name, True);
+	if (property == None) {
+		return NULL;
+	}
+
+	Atom actual_type;
+	int actual_format;
+	unsigned long bytes_after;
+	unsigned char *data = NULL;
+	if (XGetWindowProperty(display, window, property, 0, 4096, False, type,
+			&actual_type, &actual_format, count, &bytes_after, &data) != Success) {
+		return NULL;
+	}
+	if (actual_type == None) {
+		if (data != NULL) {
+			XFree(data);
+		}
+		return NULL;
+	}
+	return data;
+}
+
+static Window active_window(Display *display) {
+	unsigned long count = 0;
+	unsigned char *data = window_property(display, DefaultRootWindow(display), "_NET_ACTIVE_WINDOW", XA_WINDOW, &count);
+	if (data == NULL) {
+		return None;
+	}
+	Window window = count > 0 ? *(Window *)data : None;
+	XFree(data);
+	return window;
+}
+
+// window_title 回傳以 malloc 配置的 UTF-8 標題，呼叫端需以 free 釋放
+static char *window_title(Display *display, Window window) {
+	unsigned long count = 0;
+	Atom utf8 = XInternAtom(display, "UTF8_STRING", False);
+	unsigned char *data = window_property(display, window, "_NET_WM_NAME", utf8, &count);
+	if (data == NULL) {
+		data = window_property(display, window, "WM_NAME", XA_STRING, &count);
+	}
+	if (data == NULL) {
+		return NULL;
+	}
+
+	char *title = malloc(count + 1);
+	if (title != NULL) {
+		memcpy(title, data, count);
+		title[count] = '\0';
+	}
+	XFree(data);
+	return title;
+}
+
+// window_class 回傳以 malloc 配置的 WM_CLASS 類別名稱，呼叫端需以 free 釋放
+static char *window_class(Display *display, Window window) {
+	XClassHint hint;
+	if (!XGetClassHint(display, window, &hint)) {
+		return NULL;
+	}
+
+	char *name = NULL;
+	if (hint.res_class != NULL) {
+		name = strdup(hint.res_class);
+	}
+	if (hint.res_name != NULL) {
+		XFree(hint.res_name);
+	}
+	if (hint.res_class != NULL) {
+		XFree(hint.res_class);
+	}
+	return name;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"unsafe"
+
+	"main/internal/domain"
+)
+
+// Events 定期查詢焦點視窗，應用程式或標題改變時送出事件
+func (s *Source) Events(ctx context.Context) (<-chan domain.FocusEvent, error) {
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return nil, fmt.Errorf("無法開啟 X11 顯示器")
+	}
+	C.install_error_handler()
+
+	events := make(chan domain.FocusEvent)
+	go func() {
+		defer close(events)
+		defer C.XCloseDisplay(display)
+
+		ticker := s.clock.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		var last domain.Focus
+		first := true
+		for {
+			focus := activeFocus(display)
+			if first || focus != last {
+				first = false
+				last = focus
+
+				select {
+				case events <- domain.FocusEvent{Focus: focus, Time: s.clock.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+func activeFocus(display *C.Display) domain.Focus {
+	window := C.active_window(display)
+	if window == C.None {
+		return domain.Focus{}
+	}
+	return domain.Focus{
+		App:   takeString(C.window_class(display, window)),
+		Title: takeString(C.window_title(display, window)),
+	}
+}
+
+// takeString 複製 C 字串後釋放
+func takeString(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	defer C.free(unsafe.Pointer(s))
+	return C.GoString(s)
+}
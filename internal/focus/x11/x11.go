@@ -0,0 +1,23 @@
+package x11
+
+import (
+	"time"
+
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+var _ usecase.FocusSource = &Source{}
+
+// pollInterval 是查詢焦點視窗的間隔
+const pollInterval = time.Second
+
+// Source 依 EWMH 的 _NET_ACTIVE_WINDOW 取得焦點視窗，以 WM_CLASS 作為應用程式名稱，
+// _NET_WM_NAME（沒有時使用 WM_NAME）作為視窗標題
+type Source struct {
+	clock clock.Clock
+}
+
+func New(clk clock.Clock) *Source {
+	return &Source{clock: clk}
+}
@@ -0,0 +1,170 @@
+package logind
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+)
+
+var _ usecase.IdleSource = &Source{}
+
+const (
+	busName          = "org.freedesktop.login1"
+	managerPath      = dbus.ObjectPath("/org/freedesktop/login1")
+	managerInterface = "org.freedesktop.login1.Manager"
+	sessionInterface = "org.freedesktop.login1.Session"
+	propsInterface   = "org.freedesktop.DBus.Properties"
+)
+
+// Source 透過 D-Bus 監聽 systemd-logind 的 IdleHint、Lock/Unlock 與 PrepareForSleep
+type Source struct{}
+
+func New() *Source {
+	return &Source{}
+}
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.SystemEvent, error) {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil, fmt.Errorf("無法連接系統 D-Bus: %v", err)
+	}
+
+	sessionPath, err := currentSession(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchObjectPath(managerPath),
+			dbus.WithMatchInterface(managerInterface),
+			dbus.WithMatchMember("PrepareForSleep"),
+		},
+		{
+			dbus.WithMatchObjectPath(sessionPath),
+			dbus.WithMatchInterface(sessionInterface),
+		},
+		{
+			dbus.WithMatchObjectPath(sessionPath),
+			dbus.WithMatchInterface(propsInterface),
+			dbus.WithMatchMember("PropertiesChanged"),
+		},
+	}
+	for _, match := range matches {
+		if err := conn.AddMatchSignal(match...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("無法訂閱 logind 訊號: %v", err)
+		}
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	events := make(chan domain.SystemEvent)
+	go func() {
+		defer close(events)
+		defer conn.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case signal, ok := <-signals:
+				if !ok {
+					return
+				}
+
+				ev, ok := toSystemEvent(signal)
+				if !ok {
+					continue
+				}
+
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// currentSession 回傳這個程序所屬的 logind session
+func currentSession(conn *dbus.Conn) (dbus.ObjectPath, error) {
+	var path dbus.ObjectPath
+	err := conn.Object(busName, managerPath).
+		Call(managerInterface+".GetSessionByPID", 0, uint32(os.Getpid())).
+		Store(&path)
+	if err != nil {
+		log.Printf("無法取得目前的 logind session，改用 auto: %v", err)
+		return dbus.ObjectPath("/org/freedesktop/login1/session/auto"), nil
+	}
+	return path, nil
+}
+
+func toSystemEvent(signal *dbus.Signal) (domain.SystemEvent, bool) {
+	now := time.Now()
+
+	switch signal.Name {
+	case managerInterface + ".PrepareForSleep":
+		if len(signal.Body) == 0 {
+			return domain.SystemEvent{}, false
+		}
+		sleeping, ok := signal.Body[0].(bool)
+		if !ok {
+			return domain.SystemEvent{}, false
+		}
+		if sleeping {
+			return domain.SystemEvent{Kind: domain.SystemSuspend, Time: now}, true
+		}
+		return domain.SystemEvent{Kind: domain.SystemResume, Time: now}, true
+
+	case sessionInterface + ".Lock":
+		return domain.SystemEvent{Kind: domain.SystemLock, Time: now}, true
+
+	case sessionInterface + ".Unlock":
+		return domain.SystemEvent{Kind: domain.SystemUnlock, Time: now}, true
+
+	case propsInterface + ".PropertiesChanged":
+		// Body: interface 名稱、變更的屬性、失效的屬性
+		if len(signal.Body) < 2 {
+			return domain.SystemEvent{}, false
+		}
+		changed, ok := signal.Body[1].(map[string]dbus.Variant)
+		if !ok {
+			return domain.SystemEvent{}, false
+		}
+		hint, ok := changed["IdleHint"]
+		if !ok {
+			return domain.SystemEvent{}, false
+		}
+		idle, ok := hint.Value().(bool)
+		if !ok {
+			return domain.SystemEvent{}, false
+		}
+
+		ev := domain.SystemEvent{Kind: domain.SystemActive, Time: now}
+		if idle {
+			ev.Kind = domain.SystemIdle
+		}
+		// IdleSinceHint 是狀態改變的時間（微秒），有提供時以它為準
+		if since, ok := changed["IdleSinceHint"]; ok {
+			if usec, ok := since.Value().(uint64); ok && usec > 0 {
+				ev.Time = time.UnixMicro(int64(usec))
+			}
+		}
+		return ev, true
+	}
+
+	return domain.SystemEvent{}, false
+}
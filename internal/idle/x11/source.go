@@ -0,0 +1,87 @@
+//go:build linux && cgo
+
+package x11
+
+/*
+#cgo LDFLAGS: -lX11 -lXss
+#include <X11/Xlib.h>
+#include <X11/extensions/scrnsaver.h>
+
+// idle_ms 回傳使用者沒有輸入的毫秒數，失敗時回傳 -1
+static long idle_ms(Display *display) {
+	XScreenSaverInfo *info = XScreenSaverAllocInfo();
+	if (info == NULL) {
+		return -1;
+	}
+	if (!XScreenSaverQueryInfo(display, DefaultRootWindow(display), info)) {
+		XFree(info);
+		return -1;
+	}
+	long ms = (long)info->idle;
+	XFree(info);
+	return ms;
+}
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"main/internal/domain"
+)
+
+// Events 定期查詢 X11 螢幕保護程式擴充的閒置時間，超過閾值時送出閒置事件，
+// 閒置時間變短時送出恢復事件
+func (s *Source) Events(ctx context.Context) (<-chan domain.SystemEvent, error) {
+	display := C.XOpenDisplay(nil)
+	if display == nil {
+		return nil, fmt.Errorf("無法開啟 X11 顯示器")
+	}
+
+	events := make(chan domain.SystemEvent)
+	go func() {
+		defer close(events)
+		defer C.XCloseDisplay(display)
+
+		ticker := s.clock.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		idle := false
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+			}
+
+			ms := C.idle_ms(display)
+			if ms < 0 {
+				continue
+			}
+			idleFor := time.Duration(ms) * time.Millisecond
+			threshold := s.currentThreshold()
+
+			var ev domain.SystemEvent
+			switch {
+			case !idle && idleFor >= threshold:
+				idle = true
+				ev = domain.SystemEvent{Kind: domain.SystemIdle, Time: s.clock.Now().Add(-idleFor)}
+			case idle && idleFor < threshold:
+				idle = false
+				ev = domain.SystemEvent{Kind: domain.SystemActive, Time: s.clock.Now().Add(-idleFor)}
+			default:
+				continue
+			}
+
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux || !cgo
+
+package x11
+
+import (
+	"context"
+	"fmt"
+
+	"main/internal/domain"
+)
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.SystemEvent, error) {
+	return nil, fmt.Errorf("這個平台不支援 X11 閒置偵測")
+}
@@ -0,0 +1,42 @@
+package x11
+
+import (
+	"sync"
+	"time"
+
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+var _ usecase.ThresholdIdleSource = &Source{}
+
+// pollInterval 是查詢閒置時間的間隔
+const pollInterval = time.Second
+
+// Source 以 X11 螢幕保護程式擴充（XScreenSaver）的閒置時間判斷使用者是否閒置
+type Source struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	threshold time.Duration
+}
+
+// New 建立 X11 閒置來源，沒有輸入超過 threshold 時視為閒置
+func New(clk clock.Clock, threshold time.Duration) *Source {
+	return &Source{clock: clk, threshold: threshold}
+}
+
+// UpdateThreshold 更新閒置閾值，下次查詢閒置時間時生效
+func (s *Source) UpdateThreshold(threshold time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.threshold = threshold
+}
+
+func (s *Source) currentThreshold() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.threshold
+}
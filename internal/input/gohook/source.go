@@ -0,0 +1,101 @@
+package gohook
+
+import (
+	"context"
+	"math"
+	"time"
+
+	hook "github.com/robotn/gohook"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+var _ usecase.InputSource = &Source{}
+
+// Source 透過 gohook 監聽全域的滑鼠與鍵盤事件。
+//
+// gohook 的事件帶有按鍵代碼與字元（Keycode、Rawcode、Keychar）。
+// 這些欄位只存在於這個套件內：normalize 是唯一讀取 hook.Event 的地方，
+// 只取用事件種類與滑鼠座標，轉成不含按鍵內容的 domain.InputEvent 後才離開套件，
+// 原始事件也不會寫入記錄檔。
+type Source struct {
+	clock clock.Clock
+}
+
+func New(clk clock.Clock) *Source {
+	return &Source{clock: clk}
+}
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.InputEvent, error) {
+	evChan := hook.Start()
+	events := make(chan domain.InputEvent)
+
+	go func() {
+		defer close(events)
+		defer hook.End()
+
+		var mouse pointer
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-evChan:
+				if !ok {
+					return
+				}
+
+				input, ok := normalize(ev, &mouse, s.clock.Now())
+				if !ok {
+					continue
+				}
+
+				select {
+				case events <- input:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// normalize 將 gohook 的事件轉成 domain.InputEvent，只讀取 Kind、X 與 Y。
+// 只保留按下、捲動與移動的事件；放開按鍵與 gohook 依字元產生的 KeyDown 事件不需要，直接丟棄。
+// gohook 的 KeyHold 與 MouseHold 是按下的瞬間，事件時間為收到事件時 now 的值。
+func normalize(ev hook.Event, mouse *pointer, now time.Time) (domain.InputEvent, bool) {
+	input := domain.InputEvent{Time: now}
+
+	switch ev.Kind {
+	case hook.KeyHold:
+		input.Type, input.Kind = domain.KeyboardActivity, domain.InputKeyPress
+	case hook.MouseHold:
+		input.Type, input.Kind = domain.MouseActivity, domain.InputClick
+	case hook.MouseWheel:
+		input.Type, input.Kind = domain.MouseActivity, domain.InputScroll
+	case hook.MouseMove, hook.MouseDrag:
+		input.Type, input.Kind = domain.MouseActivity, domain.InputMove
+		input.Distance = mouse.moveTo(ev.X, ev.Y)
+	default:
+		return domain.InputEvent{}, false
+	}
+	return input, true
+}
+
+// pointer 記錄上一次的滑鼠位置，用於計算移動距離
+type pointer struct {
+	x, y  int16
+	valid bool
+}
+
+func (p *pointer) moveTo(x, y int16) float64 {
+	var distance float64
+	if p.valid {
+		distance = math.Hypot(float64(x)-float64(p.x), float64(y)-float64(p.y))
+	}
+	p.x, p.y, p.valid = x, y, true
+	return distance
+}
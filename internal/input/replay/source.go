@@ -0,0 +1,106 @@
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+var _ usecase.InputSource = &Source{}
+
+// record 是重播檔案中的一行，例如 {"time":"2024-01-02T09:00:00+08:00","type":"mouse"}。
+// kind 與 distance 可省略，例如 {"time":"...","type":"mouse","kind":"move","distance":42.5}
+type record struct {
+	Time     time.Time           `json:"time"`
+	Type     domain.ActivityType `json:"type"`
+	Kind     domain.InputKind    `json:"kind,omitempty"`
+	Distance float64             `json:"distance,omitempty"`
+}
+
+// Source 從 JSON Lines 檔案讀取事件，並依照原始的時間間隔重播
+type Source struct {
+	clock clock.Clock
+	path  string
+	speed float64
+}
+
+// New 建立重播來源，speed 為播放倍速，小於等於 0 時視為 1
+func New(clk clock.Clock, path string, speed float64) *Source {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &Source{clock: clk, path: path, speed: speed}
+}
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.InputEvent, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("無法開啟重播檔案: %v", err)
+	}
+
+	events := make(chan domain.InputEvent)
+
+	go func() {
+		defer close(events)
+		defer file.Close()
+
+		if err := s.replay(ctx, file, events); err != nil {
+			log.Printf("重播輸入事件失敗: %v", err)
+		}
+	}()
+
+	return events, nil
+}
+
+func (s *Source) replay(ctx context.Context, r io.Reader, events chan<- domain.InputEvent) error {
+	scanner := bufio.NewScanner(r)
+
+	var previous time.Time
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("第 %d 行格式錯誤: %v", line, err)
+		}
+		if rec.Type != domain.MouseActivity && rec.Type != domain.KeyboardActivity {
+			return fmt.Errorf("第 %d 行活動類型錯誤: %q", line, rec.Type)
+		}
+		switch rec.Kind {
+		case domain.InputOther, domain.InputKeyPress, domain.InputClick, domain.InputScroll, domain.InputMove:
+		default:
+			return fmt.Errorf("第 %d 行事件種類錯誤: %q", line, rec.Kind)
+		}
+
+		if !previous.IsZero() && rec.Time.After(previous) {
+			wait := time.Duration(float64(rec.Time.Sub(previous)) / s.speed)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-s.clock.After(wait):
+			}
+		}
+		previous = rec.Time
+
+		select {
+		case events <- domain.InputEvent{Type: rec.Type, Time: s.clock.Now(), Kind: rec.Kind, Distance: rec.Distance}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,56 @@
+package scripted
+
+import (
+	"context"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+var _ usecase.InputSource = &Source{}
+
+// Step 是腳本中的一個事件，After 為距離上一個事件的間隔
+type Step struct {
+	After    time.Duration
+	Type     domain.ActivityType
+	Kind     domain.InputKind // 可省略，省略時不計入輸入強度
+	Distance float64          // 滑鼠移動的距離，只在 Kind 為 domain.InputMove 時使用
+}
+
+// Source 依照預先寫好的腳本產生事件，用於測試與展示
+type Source struct {
+	clock clock.Clock
+	steps []Step
+}
+
+func New(clk clock.Clock, steps ...Step) *Source {
+	return &Source{clock: clk, steps: steps}
+}
+
+func (s *Source) Events(ctx context.Context) (<-chan domain.InputEvent, error) {
+	events := make(chan domain.InputEvent)
+
+	go func() {
+		defer close(events)
+
+		for _, step := range s.steps {
+			if step.After > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-s.clock.After(step.After):
+				}
+			}
+
+			select {
+			case events <- domain.InputEvent{Type: step.Type, Time: s.clock.Now(), Kind: step.Kind, Distance: step.Distance}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
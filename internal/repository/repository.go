@@ -2,14 +2,95 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"iter"
+	"time"
 
 	"main/internal/domain"
 )
 
+var (
+	ErrActivityNotFound = errors.New("找不到活動記錄")
+	ErrProjectNotFound  = errors.New("找不到專案")
+	ErrTagNotFound      = errors.New("找不到標籤")
+)
+
 type ActivityRepository interface {
-	Save(ctx context.Context, activity domain.Activity) error
+	Save(ctx context.Context, activity domain.Activity) (domain.Activity, error)
 	UpdateEndTime(ctx context.Context, activity domain.Activity) error
 	GetActivities(ctx context.Context) ([]domain.Activity, error)
-	GetTodayActivities(ctx context.Context) ([]domain.Activity, error)
-	CleanupUnfinishedActivities(ctx context.Context) error
+	GetTodayActivities(ctx context.Context, cal domain.Calendar) ([]domain.Activity, error)
+	// GetActivitiesBetween 依開始時間順序串流與 [from, to) 重疊的活動，types 為空時不篩選類型
+	GetActivitiesBetween(ctx context.Context, from, to time.Time, types ...domain.ActivityType) iter.Seq2[domain.Activity, error]
+	// GetActivity 依 ID 回傳活動
+	GetActivity(ctx context.Context, id int64) (domain.Activity, error)
+	// UpdateActivity 修改已結束活動的時間、類型、專案與手動標記，同時調整彙總，稽核記錄的來源為 source
+	UpdateActivity(ctx context.Context, activity domain.Activity, source domain.AuditSource) error
+	// SplitActivity 在 at 將已結束的活動分成兩筆並標示為手動修改，後半段沿用原本的專案與標籤
+	SplitActivity(ctx context.Context, id int64, at time.Time, source domain.AuditSource) (domain.Activity, domain.Activity, error)
+	// ReclassifyActivity 將已結束的活動在各段的開始時間分割並設定各段的專案，第一段需從活動開始時間開始。
+	// 第一段沿用原本的 ID，其餘各段以新的 ID 新增並複製標籤，manual 維持不變
+	ReclassifyActivity(ctx context.Context, id int64, segments []domain.ProjectSegment, source domain.AuditSource) ([]domain.Activity, error)
+	// DeleteActivity 刪除活動與它的標籤，同時調整彙總，稽核記錄中保留刪除前的活動與標籤
+	DeleteActivity(ctx context.Context, id int64, source domain.AuditSource) error
+	// RestoreActivity 以新的 ID 重新加入已刪除的活動與它的標籤，activity.ID 為刪除前的 ID
+	RestoreActivity(ctx context.Context, activity domain.Activity, tagIDs []int64) (domain.Activity, error)
+	// SaveHeartbeat 保存各類型最後一次輸入的時間，整體的心跳取其中最晚的時間
+	SaveHeartbeat(ctx context.Context, lastSeen map[domain.ActivityType]int64) error
+	// SaveGap 記錄休眠等無法記錄活動的時段
+	SaveGap(ctx context.Context, gap domain.Gap) (domain.Gap, error)
+	// GetGapsBetween 依開始時間順序回傳與 [from, to) 重疊的時段
+	GetGapsBetween(ctx context.Context, from, to time.Time) ([]domain.Gap, error)
+	// StartFocus 記錄從 at 開始取得焦點的視窗
+	StartFocus(ctx context.Context, focus domain.Focus, at time.Time) (domain.FocusPeriod, error)
+	// EndFocus 設定焦點時段的結束時間
+	EndFocus(ctx context.Context, id int64, at time.Time) error
+	// GetFocusBetween 依開始時間順序回傳與 [from, to) 重疊的焦點時段
+	GetFocusBetween(ctx context.Context, from, to time.Time) ([]domain.FocusPeriod, error)
+	RecoverUnfinishedActivities(ctx context.Context, thresholdSeconds int) (domain.RecoveryReport, error)
+	// UseCalendar 設定每日彙總的每日邊界，必要時重新建立每日彙總
+	UseCalendar(ctx context.Context, cal domain.Calendar) error
+	// RebuildRollups 從原始活動記錄重新產生所有彙總
+	RebuildRollups(ctx context.Context) error
+	// ApplyRetention 依 plan 合併與刪除舊活動，dryRun 時只回報結果不寫入
+	ApplyRetention(ctx context.Context, plan domain.RetentionPlan, dryRun bool) (domain.RetentionReport, error)
+}
+
+type StatsRepository interface {
+	// GetDailyStats 回傳已結束活動依 cal 切分後的每日統計，總時間為各類型時間的聯集，
+	// Apps 為活動時間依焦點時段分配到各應用程式的結果，最新的日期在前
+	GetDailyStats(ctx context.Context, cal domain.Calendar) ([]domain.DailyStats, error)
+	// GetDailyStatsSince 同 GetDailyStats，但只回傳 since 所在的那一天與之後的日期，
+	// 讓活動結束時只需重新彙總受影響的日期
+	GetDailyStatsSince(ctx context.Context, cal domain.Calendar, since time.Time) ([]domain.DailyStats, error)
+	// GetHourlyStats 回傳 [from, to) 之間每小時的已結束活動時間
+	GetHourlyStats(ctx context.Context, from, to time.Time) ([]domain.HourlyStats, error)
+}
+
+// ProjectRepository 管理專案、標籤以及活動與標籤的多對多關係
+type ProjectRepository interface {
+	CreateProject(ctx context.Context, name string) (domain.Project, error)
+	GetProject(ctx context.Context, id int64) (domain.Project, error)
+	// GetProjects 依名稱順序回傳所有專案
+	GetProjects(ctx context.Context) ([]domain.Project, error)
+	CreateTag(ctx context.Context, name string) (domain.Tag, error)
+	// GetTags 依名稱順序回傳所有標籤
+	GetTags(ctx context.Context) ([]domain.Tag, error)
+	// SetActivityTags 以 tagIDs 取代活動原本的標籤，稽核記錄的來源為 source
+	SetActivityTags(ctx context.Context, activityID int64, tagIDs []int64, source domain.AuditSource) error
+	// GetActivityTags 依名稱順序回傳活動的標籤
+	GetActivityTags(ctx context.Context, activityID int64) ([]domain.Tag, error)
+}
+
+// AuditRepository 讀取活動變更的稽核記錄，記錄由各個變更活動的方法在同一個交易中寫入
+type AuditRepository interface {
+	// GetAuditLog 依時間順序回傳在 [from, to) 之間寫入的稽核記錄
+	GetAuditLog(ctx context.Context, from, to time.Time) ([]domain.AuditEntry, error)
+}
+
+type IntensityRepository interface {
+	// AddIntensity 將每分鐘的輸入次數累加到已記錄的數值上
+	AddIntensity(ctx context.Context, counters []domain.InputIntensity) error
+	// GetIntensity 回傳 [from, to) 之間每分鐘的輸入強度，依時間排序
+	GetIntensity(ctx context.Context, from, to time.Time) ([]domain.InputIntensity, error)
 }
@@ -0,0 +1,345 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+func (r *SQLiteActivityRepository) GetActivity(ctx context.Context, id int64) (domain.Activity, error) {
+	return getActivity(ctx, r.db.QueryRowContext, id)
+}
+
+// getActivity 以 query 讀取一筆活動，讓交易內外共用同一段查詢
+func getActivity(ctx context.Context, query func(context.Context, string, ...interface{}) *sql.Row, id int64) (domain.Activity, error) {
+	activity, err := scanActivity(query(ctx, `
+		SELECT id, start_time, end_time, activity_type, project_id, manual
+		FROM activities
+		WHERE id = ?
+	`, id))
+	if err == sql.ErrNoRows {
+		return activity, fmt.Errorf("%w: ID=%d", repository.ErrActivityNotFound, id)
+	}
+	return activity, err
+}
+
+func (r *SQLiteActivityRepository) UpdateActivity(ctx context.Context, activity domain.Activity, source domain.AuditSource) error {
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	previous, err := getActivity(ctx, tx.QueryRowContext, activity.ID)
+	if err != nil {
+		return err
+	}
+	if err := delta.add(ctx, tx, previous, -1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE activities
+		SET start_time = ?, end_time = ?, activity_type = ?, project_id = ?, manual = ?
+		WHERE id = ?
+	`, activity.StartTimeUnix, activity.EndTimeUnix, activity.Type, nullableID(activity.ProjectID), activity.Manual, activity.ID); err != nil {
+		log.Printf("修改活動失敗: %v", err)
+		return err
+	}
+
+	if err := delta.add(ctx, tx, activity, 1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return err
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return err
+	}
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     source,
+		Operation:  domain.AuditUpdate,
+		ActivityID: activity.ID,
+		Before:     &previous,
+		After:      &activity,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteActivityRepository) SplitActivity(ctx context.Context, id int64, at time.Time, source domain.AuditSource) (domain.Activity, domain.Activity, error) {
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return domain.Activity{}, domain.Activity{}, err
+	}
+	defer done()
+
+	original, err := getActivity(ctx, tx.QueryRowContext, id)
+	if err != nil {
+		return domain.Activity{}, domain.Activity{}, err
+	}
+	if !original.IsEnded() || at.Unix() <= original.StartTimeUnix || at.Unix() >= original.EndTimeUnix {
+		return domain.Activity{}, domain.Activity{}, fmt.Errorf("分割時間必須在已結束活動的開始與結束之間")
+	}
+	if err := delta.add(ctx, tx, original, -1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return domain.Activity{}, domain.Activity{}, err
+	}
+
+	first := original
+	first.EndTimeUnix = at.Unix()
+	first.Manual = true
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE activities SET end_time = ?, manual = 1 WHERE id = ?
+	`, first.EndTimeUnix, first.ID); err != nil {
+		log.Printf("分割活動失敗: %v", err)
+		return domain.Activity{}, domain.Activity{}, err
+	}
+
+	second := original
+	second.StartTimeUnix = at.Unix()
+	second.Manual = true
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO activities (start_time, end_time, activity_type, project_id, manual)
+		VALUES (?, ?, ?, ?, 1)
+	`, second.StartTimeUnix, second.EndTimeUnix, second.Type, nullableID(second.ProjectID))
+	if err != nil {
+		log.Printf("分割活動失敗: %v", err)
+		return domain.Activity{}, domain.Activity{}, err
+	}
+	if second.ID, err = result.LastInsertId(); err != nil {
+		return domain.Activity{}, domain.Activity{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO activity_tags (activity_id, tag_id)
+		SELECT ?, tag_id FROM activity_tags WHERE activity_id = ?
+	`, second.ID, first.ID); err != nil {
+		log.Printf("複製活動標籤失敗: %v", err)
+		return domain.Activity{}, domain.Activity{}, err
+	}
+
+	for _, part := range []domain.Activity{first, second} {
+		if err := delta.add(ctx, tx, part, 1); err != nil {
+			log.Printf("計算活動彙總失敗: %v", err)
+			return domain.Activity{}, domain.Activity{}, err
+		}
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return domain.Activity{}, domain.Activity{}, err
+	}
+
+	now := r.clock.Now()
+	detail := fmt.Sprintf("在 %s 分割活動 ID=%d", at.Format(time.DateTime), original.ID)
+	for _, entry := range []domain.AuditEntry{
+		{Operation: domain.AuditUpdate, ActivityID: first.ID, Before: &original, After: &first},
+		{Operation: domain.AuditInsert, ActivityID: second.ID, After: &second},
+	} {
+		entry.Time = now
+		entry.Source = source
+		entry.Detail = detail
+		if err := writeAudit(ctx, tx, entry); err != nil {
+			return domain.Activity{}, domain.Activity{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Activity{}, domain.Activity{}, err
+	}
+	return first, second, nil
+}
+
+func (r *SQLiteActivityRepository) ReclassifyActivity(ctx context.Context, id int64, segments []domain.ProjectSegment, source domain.AuditSource) ([]domain.Activity, error) {
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	original, err := getActivity(ctx, tx.QueryRowContext, id)
+	if err != nil {
+		return nil, err
+	}
+	if !original.IsEnded() || len(segments) == 0 || segments[0].Start.Unix() != original.StartTimeUnix {
+		return nil, fmt.Errorf("重新歸類的第一段必須從已結束活動的開始時間開始")
+	}
+	for i := 1; i < len(segments); i++ {
+		start := segments[i].Start.Unix()
+		if start <= segments[i-1].Start.Unix() || start >= original.EndTimeUnix {
+			return nil, fmt.Errorf("重新歸類的各段必須依時間順序且在活動結束之前開始")
+		}
+	}
+	if err := delta.add(ctx, tx, original, -1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return nil, err
+	}
+
+	parts := make([]domain.Activity, len(segments))
+	for i, segment := range segments {
+		part := original
+		part.StartTimeUnix = segment.Start.Unix()
+		if i+1 < len(segments) {
+			part.EndTimeUnix = segments[i+1].Start.Unix()
+		}
+		part.ProjectID = segment.ProjectID
+
+		if i == 0 {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE activities SET end_time = ?, project_id = ? WHERE id = ?
+			`, part.EndTimeUnix, nullableID(part.ProjectID), part.ID); err != nil {
+				log.Printf("重新歸類活動失敗: %v", err)
+				return nil, err
+			}
+		} else {
+			result, err := tx.ExecContext(ctx, `
+				INSERT INTO activities (start_time, end_time, activity_type, project_id, manual)
+				VALUES (?, ?, ?, ?, ?)
+			`, part.StartTimeUnix, part.EndTimeUnix, part.Type, nullableID(part.ProjectID), part.Manual)
+			if err != nil {
+				log.Printf("重新歸類活動失敗: %v", err)
+				return nil, err
+			}
+			if part.ID, err = result.LastInsertId(); err != nil {
+				return nil, err
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO activity_tags (activity_id, tag_id)
+				SELECT ?, tag_id FROM activity_tags WHERE activity_id = ?
+			`, part.ID, original.ID); err != nil {
+				log.Printf("複製活動標籤失敗: %v", err)
+				return nil, err
+			}
+		}
+
+		if err := delta.add(ctx, tx, part, 1); err != nil {
+			log.Printf("計算活動彙總失敗: %v", err)
+			return nil, err
+		}
+		parts[i] = part
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return nil, err
+	}
+
+	now := r.clock.Now()
+	detail := fmt.Sprintf("依規則將活動 ID=%d 分成 %d 段", original.ID, len(parts))
+	for i := range parts {
+		entry := domain.AuditEntry{Operation: domain.AuditInsert, ActivityID: parts[i].ID, After: &parts[i]}
+		if i == 0 {
+			entry.Operation, entry.Before = domain.AuditUpdate, &original
+		}
+		entry.Time = now
+		entry.Source = source
+		entry.Detail = detail
+		if err := writeAudit(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+func (r *SQLiteActivityRepository) DeleteActivity(ctx context.Context, id int64, source domain.AuditSource) error {
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	activity, err := getActivity(ctx, tx.QueryRowContext, id)
+	if err != nil {
+		return err
+	}
+	if err := delta.add(ctx, tx, activity, -1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return err
+	}
+
+	tagIDs, err := activityTagIDs(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := deleteActivityRow(ctx, tx, id); err != nil {
+		return err
+	}
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     source,
+		Operation:  domain.AuditDelete,
+		ActivityID: id,
+		Before:     &activity,
+		Tags:       tagIDs,
+	}); err != nil {
+		return err
+	}
+
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SQLiteActivityRepository) RestoreActivity(ctx context.Context, activity domain.Activity, tagIDs []int64) (domain.Activity, error) {
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return activity, err
+	}
+	defer done()
+
+	restored := activity
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO activities (start_time, end_time, activity_type, project_id, manual)
+		VALUES (?, ?, ?, ?, ?)
+	`, activity.StartTimeUnix, activity.EndTimeUnix, activity.Type, nullableID(activity.ProjectID), activity.Manual)
+	if err != nil {
+		log.Printf("還原活動失敗: %v", err)
+		return activity, err
+	}
+	if restored.ID, err = result.LastInsertId(); err != nil {
+		return activity, err
+	}
+
+	for _, tagID := range tagIDs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO activity_tags (activity_id, tag_id)
+			SELECT ?, id FROM tags WHERE id = ?
+		`, restored.ID, tagID); err != nil {
+			log.Printf("還原活動標籤失敗: %v", err)
+			return activity, err
+		}
+	}
+
+	if err := delta.add(ctx, tx, restored, 1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return activity, err
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return activity, err
+	}
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     domain.AuditUndo,
+		Operation:  domain.AuditInsert,
+		ActivityID: restored.ID,
+		After:      &restored,
+		Detail:     fmt.Sprintf("還原已刪除的活動 ID=%d", activity.ID),
+	}); err != nil {
+		return activity, err
+	}
+	if err := tx.Commit(); err != nil {
+		return activity, err
+	}
+	return restored, nil
+}
@@ -3,63 +3,170 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"iter"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"main/internal/domain"
 	"main/internal/repository"
+	"main/pkg/clock"
 )
 
 var _ repository.ActivityRepository = &SQLiteActivityRepository{}
 
+// activityPageSize 是 GetActivitiesBetween 每次向資料庫讀取的筆數
+const activityPageSize = 500
+
+// SQLiteActivityRepository 在活動結束時以同一個交易更新每小時與每日彙總
 type SQLiteActivityRepository struct {
-	db *sql.DB
+	db    *sql.DB
+	clock clock.Clock
+
+	rollupMu  sync.Mutex       // 讓更新彙總的交易依序執行，並保護 rollupCal
+	rollupCal *domain.Calendar // 每日彙總的每日邊界，nil 表示尚未呼叫 UseCalendar
 }
 
-func NewSQLiteActivityRepository(db *sql.DB) *SQLiteActivityRepository {
-	return &SQLiteActivityRepository{db: db}
+func NewSQLiteActivityRepository(db *sql.DB, clk clock.Clock) *SQLiteActivityRepository {
+	return &SQLiteActivityRepository{db: db, clock: clk}
 }
 
-func (r *SQLiteActivityRepository) Save(ctx context.Context, activity domain.Activity) error {
+func (r *SQLiteActivityRepository) Save(ctx context.Context, activity domain.Activity) (domain.Activity, error) {
 	var endTime interface{}
 	if activity.EndTimeUnix > 0 {
 		endTime = activity.EndTimeUnix
 	}
 
-	result, err := r.db.ExecContext(ctx, `
-		INSERT INTO activities (start_time, end_time, activity_type)
-		VALUES (?, ?, ?)
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return activity, err
+	}
+	defer done()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO activities (start_time, end_time, activity_type, project_id, manual)
+		VALUES (?, ?, ?, ?, ?)
 	`,
 		activity.StartTimeUnix,
 		endTime,
 		activity.Type,
+		nullableID(activity.ProjectID),
+		activity.Manual,
 	)
 	if err != nil {
 		log.Printf("保存活動失敗: %v", err)
-		return err
+		return activity, err
 	}
 
-	id, _ := result.LastInsertId()
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Printf("獲取活動 ID 失敗: %v", err)
+		return activity, err
+	}
+
+	saved := activity
+	saved.ID = id
+	source := domain.AuditTracker
+	if activity.Manual {
+		source = domain.AuditManual
+	}
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     source,
+		Operation:  domain.AuditInsert,
+		ActivityID: id,
+		After:      &saved,
+	}); err != nil {
+		return activity, err
+	}
+
+	if err := delta.add(ctx, tx, activity, 1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return activity, err
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return activity, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return activity, err
+	}
+
+	activity.ID = id
 	log.Printf("保存活動成功: ID=%d", id)
-	return nil
+	return activity, nil
 }
 
 func (r *SQLiteActivityRepository) UpdateEndTime(ctx context.Context, activity domain.Activity) error {
-	_, err := r.db.ExecContext(ctx, `
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	previous, err := getActivity(ctx, tx.QueryRowContext, activity.ID)
+	if err != nil {
+		log.Printf("查詢活動失敗: %v", err)
+		return err
+	}
+
+	// 彙總以資料庫中的開始時間與類型為準
+	if err := delta.add(ctx, tx, previous, -1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
 		UPDATE activities 
 		SET end_time = ? 
-		WHERE start_time = ? AND activity_type = ?
+		WHERE id = ?
 	`,
 		activity.EndTimeUnix,
-		activity.StartTimeUnix,
-		activity.Type,
+		activity.ID,
 	)
-	return err
+	if err != nil {
+		log.Printf("更新活動結束時間失敗: %v", err)
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: ID=%d", repository.ErrActivityNotFound, activity.ID)
+	}
+
+	updated := previous
+	updated.EndTimeUnix = activity.EndTimeUnix
+	if err := delta.add(ctx, tx, updated, 1); err != nil {
+		log.Printf("計算活動彙總失敗: %v", err)
+		return err
+	}
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     domain.AuditTracker,
+		Operation:  domain.AuditUpdate,
+		ActivityID: activity.ID,
+		Before:     &previous,
+		After:      &updated,
+	}); err != nil {
+		return err
+	}
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (r *SQLiteActivityRepository) GetActivities(ctx context.Context) ([]domain.Activity, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, start_time, end_time, activity_type 
+		SELECT id, start_time, end_time, activity_type, project_id, manual
 		FROM activities 
 		ORDER BY start_time DESC
 	`)
@@ -72,10 +179,10 @@ func (r *SQLiteActivityRepository) GetActivities(ctx context.Context) ([]domain.
 	var activities []domain.Activity
 	for rows.Next() {
 		var activity domain.Activity
-		var startTime, endTime sql.NullInt64
+		var startTime, endTime, projectID sql.NullInt64
 		var activityType string
 
-		if err := rows.Scan(&activity.ID, &startTime, &endTime, &activityType); err != nil {
+		if err := rows.Scan(&activity.ID, &startTime, &endTime, &activityType, &projectID, &activity.Manual); err != nil {
 			log.Printf("掃描活動資料失敗: %v", err)
 			return nil, err
 		}
@@ -85,6 +192,7 @@ func (r *SQLiteActivityRepository) GetActivities(ctx context.Context) ([]domain.
 			activity.EndTimeUnix = endTime.Int64
 		}
 		activity.Type = domain.ActivityType(activityType)
+		activity.ProjectID = projectID.Int64
 
 		activities = append(activities, activity)
 	}
@@ -93,65 +201,348 @@ func (r *SQLiteActivityRepository) GetActivities(ctx context.Context) ([]domain.
 	return activities, rows.Err()
 }
 
-func (r *SQLiteActivityRepository) GetTodayActivities(ctx context.Context) ([]domain.Activity, error) {
-	today := time.Now().Truncate(24 * time.Hour).Unix()
-	tomorrow := today + 24*60*60
+func (r *SQLiteActivityRepository) GetTodayActivities(ctx context.Context, cal domain.Calendar) ([]domain.Activity, error) {
+	todayStart, tomorrowStart := cal.DayRange(r.clock.Now())
 
 	log.Printf("查詢今日活動: 開始時間=%v, 結束時間=%v",
-		time.Unix(today, 0).Format(time.RFC3339),
-		time.Unix(tomorrow, 0).Format(time.RFC3339))
+		todayStart.Format(time.RFC3339),
+		tomorrowStart.Format(time.RFC3339))
 
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, start_time, end_time, activity_type 
-		FROM activities 
-		WHERE start_time >= ? AND start_time < ?
-		ORDER BY start_time ASC
-	`, today, tomorrow)
+	var activities []domain.Activity
+	for activity, err := range r.GetActivitiesBetween(ctx, todayStart, tomorrowStart) {
+		if err != nil {
+			log.Printf("查詢失敗: %v", err)
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	log.Printf("成功查詢到 %d 筆活動記錄", len(activities))
+	return activities, nil
+}
+
+// GetActivitiesBetween 依開始時間順序逐筆回傳與 [from, to) 重疊的活動，可用 types 篩選類型。
+// 每次只向資料庫讀取一頁，讀取期間不會長時間佔用連線。
+func (r *SQLiteActivityRepository) GetActivitiesBetween(ctx context.Context, from, to time.Time, types ...domain.ActivityType) iter.Seq2[domain.Activity, error] {
+	return func(yield func(domain.Activity, error) bool) {
+		query := `
+			SELECT id, start_time, end_time, activity_type, project_id, manual
+			FROM activities
+			WHERE start_time < ? AND (end_time IS NULL OR end_time = 0 OR end_time > ?)
+				AND start_time >= ? AND (start_time > ? OR (start_time = ? AND id > ?))`
+		args := []interface{}{to.Unix(), from.Unix()}
+
+		if len(types) > 0 {
+			placeholders := make([]string, len(types))
+			for i, activityType := range types {
+				placeholders[i] = "?"
+				args = append(args, activityType)
+			}
+			query += ` AND activity_type IN (` + strings.Join(placeholders, ", ") + `)`
+		}
+		query += `
+			ORDER BY start_time ASC, id ASC
+			LIMIT ?`
+
+		// 以 (start_time, id) 作為分頁游標，從最早可能與範圍重疊的活動開始，不必走過整個索引
+		lastStart, err := r.earliestOverlappingStart(ctx, from)
+		if err != nil {
+			yield(domain.Activity{}, err)
+			return
+		}
+		var lastID int64
+		for {
+			page, err := r.queryActivityPage(ctx, query, args, lastStart, lastID)
+			if err != nil {
+				yield(domain.Activity{}, err)
+				return
+			}
+
+			for _, activity := range page {
+				if !yield(activity, nil) {
+					return
+				}
+			}
+
+			if len(page) < activityPageSize {
+				return
+			}
+			last := page[len(page)-1]
+			lastStart, lastID = last.StartTimeUnix, last.ID
+		}
+	}
+}
+
+// earliestOverlappingStart 回傳與 from 之後重疊的活動最早的開始時間：已結束的活動不會早於 from 減去最長的活動時間，
+// 進行中的活動則取最早開始的一筆，兩者都能由索引直接取得
+func (r *SQLiteActivityRepository) earliestOverlappingStart(ctx context.Context, from time.Time) (int64, error) {
+	var start int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MIN(
+			? - (
+				SELECT COALESCE(MAX(end_time - start_time), 0)
+				FROM activities
+				WHERE end_time IS NOT NULL
+			),
+			COALESCE((
+				SELECT MIN(start_time)
+				FROM activities
+				WHERE end_time IS NULL OR end_time = 0
+			), ?)
+		)
+	`, from.Unix(), from.Unix()).Scan(&start)
+	if err != nil {
+		log.Printf("查詢活動範圍失敗: %v", err)
+		return 0, err
+	}
+	return start, nil
+}
+
+func (r *SQLiteActivityRepository) queryActivityPage(ctx context.Context, query string, args []interface{}, lastStart, lastID int64) ([]domain.Activity, error) {
+	pageArgs := make([]interface{}, 0, len(args)+5)
+	pageArgs = append(pageArgs, args[:2]...)
+	// start_time >= lastStart 讓 SQLite 以索引範圍從游標開始讀取
+	pageArgs = append(pageArgs, lastStart, lastStart, lastStart, lastID)
+	pageArgs = append(pageArgs, args[2:]...)
+	pageArgs = append(pageArgs, activityPageSize)
+
+	rows, err := r.db.QueryContext(ctx, query, pageArgs...)
 	if err != nil {
-		log.Printf("查詢失敗: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var activities []domain.Activity
+	page := make([]domain.Activity, 0, activityPageSize)
 	for rows.Next() {
-		var activity domain.Activity
-		var endTime sql.NullInt64
-
-		if err := rows.Scan(&activity.ID, &activity.StartTimeUnix, &endTime, &activity.Type); err != nil {
+		activity, err := scanActivity(rows)
+		if err != nil {
 			log.Printf("掃描資料失敗: %v", err)
 			return nil, err
 		}
+		page = append(page, activity)
+	}
+	return page, rows.Err()
+}
 
-		if endTime.Valid {
-			activity.EndTimeUnix = endTime.Int64
+// scanActivity 讀取 id, start_time, end_time, activity_type, project_id, manual 六個欄位
+func scanActivity(rows rowScanner) (domain.Activity, error) {
+	var activity domain.Activity
+	var endTime, projectID sql.NullInt64
+
+	if err := rows.Scan(&activity.ID, &activity.StartTimeUnix, &endTime, &activity.Type, &projectID, &activity.Manual); err != nil {
+		return activity, err
+	}
+
+	if endTime.Valid {
+		activity.EndTimeUnix = endTime.Int64
+	}
+	activity.ProjectID = projectID.Int64
+	return activity, nil
+}
+
+// rowScanner 是 *sql.Row 與 *sql.Rows 共同的讀取方法
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// nullableID 將 0 轉成 NULL，用於選填的外鍵欄位
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (r *SQLiteActivityRepository) SaveHeartbeat(ctx context.Context, lastSeen map[domain.ActivityType]int64) error {
+	if len(lastSeen) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var latest int64
+	for activityType, lastSeenUnix := range lastSeen {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO activity_heartbeats (activity_type, last_seen)
+			VALUES (?, ?)
+			ON CONFLICT(activity_type) DO UPDATE SET last_seen = excluded.last_seen
+		`, activityType, lastSeenUnix); err != nil {
+			log.Printf("寫入心跳失敗: 類型=%v, %v", activityType, err)
+			return err
 		}
+		latest = max(latest, lastSeenUnix)
+	}
 
-		activities = append(activities, activity)
+	// 整體的心跳用於結束焦點時段，以及復原升級前沒有各類型心跳的活動
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO heartbeat (id, last_seen)
+		VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET last_seen = MAX(last_seen, excluded.last_seen)
+	`, latest); err != nil {
+		log.Printf("寫入心跳失敗: %v", err)
+		return err
 	}
 
-	log.Printf("成功查詢到 %d 筆活動記錄", len(activities))
-	return activities, nil
+	return tx.Commit()
 }
 
-func (r *SQLiteActivityRepository) CleanupUnfinishedActivities(ctx context.Context) error {
-	result, err := r.db.ExecContext(ctx, `
-		DELETE FROM activities 
+// RecoverUnfinishedActivities 為沒有結束時間的活動補上結束時間：
+// 優先使用上次執行寫入的同類型心跳，否則以開始時間加上閾值作為結束時間。
+// 沒有結束時間的焦點時段以整體的心跳結束。
+func (r *SQLiteActivityRepository) RecoverUnfinishedActivities(ctx context.Context, thresholdSeconds int) (domain.RecoveryReport, error) {
+	var report domain.RecoveryReport
+
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer done()
+
+	var heartbeat int64
+	err = tx.QueryRowContext(ctx, `SELECT last_seen FROM heartbeat WHERE id = 1`).Scan(&heartbeat)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("讀取心跳失敗: %v", err)
+		return report, err
+	}
+	if heartbeat > 0 {
+		report.Heartbeat = time.Unix(heartbeat, 0)
+	}
+
+	heartbeats, err := loadHeartbeats(ctx, tx)
+	if err != nil {
+		log.Printf("讀取心跳失敗: %v", err)
+		return report, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, start_time, end_time, activity_type, project_id, manual
+		FROM activities
 		WHERE end_time IS NULL OR end_time = 0
+		ORDER BY start_time ASC
 	`)
 	if err != nil {
-		log.Printf("清理未完成活動失敗: %v", err)
-		return err
+		log.Printf("查詢未完成活動失敗: %v", err)
+		return report, err
 	}
 
-	count, err := result.RowsAffected()
+	var unfinished []domain.Activity
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			rows.Close()
+			log.Printf("掃描未完成活動失敗: %v", err)
+			return report, err
+		}
+		unfinished = append(unfinished, activity)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	for i, activity := range unfinished {
+		source := domain.RecoveryFromThreshold
+		endTime := activity.StartTimeUnix + int64(thresholdSeconds)
+		lastSeen, ok := heartbeats[activity.Type]
+		if !ok {
+			// 升級前只有整體的心跳
+			lastSeen = heartbeat
+		}
+		if lastSeen >= activity.StartTimeUnix {
+			source = domain.RecoveryFromHeartbeat
+			endTime = lastSeen
+		}
+
+		// 不讓復原的活動與下一筆同類型的未完成活動重疊
+		for _, next := range unfinished[i+1:] {
+			if next.Type == activity.Type {
+				endTime = min(endTime, next.StartTimeUnix)
+				break
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE activities
+			SET end_time = ?
+			WHERE id = ?
+		`, endTime, activity.ID); err != nil {
+			log.Printf("復原活動失敗: ID=%d, %v", activity.ID, err)
+			return report, err
+		}
+
+		previous := activity
+		activity.EndTimeUnix = endTime
+		if err := delta.add(ctx, tx, activity, 1); err != nil {
+			log.Printf("計算活動彙總失敗: %v", err)
+			return report, err
+		}
+		if err := writeAudit(ctx, tx, domain.AuditEntry{
+			Time:       r.clock.Now(),
+			Source:     domain.AuditRecovery,
+			Operation:  domain.AuditUpdate,
+			ActivityID: activity.ID,
+			Before:     &previous,
+			After:      &activity,
+			Detail:     string(source),
+		}); err != nil {
+			return report, err
+		}
+		report.Recovered = append(report.Recovered, domain.RecoveredActivity{
+			Activity: activity,
+			Source:   source,
+		})
+	}
+
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return report, err
+	}
+
+	// 未結束的焦點時段以心跳作為結束時間，心跳早於開始時間時視為沒有持續時間
+	result, err := tx.ExecContext(ctx, `
+		UPDATE focus_periods
+		SET end_time = MAX(start_time, ?)
+		WHERE end_time IS NULL
+	`, heartbeat)
 	if err != nil {
-		log.Printf("獲取影響行數失敗: %v", err)
-		return err
+		log.Printf("復原焦點時段失敗: %v", err)
+		return report, err
+	}
+	focus, err := result.RowsAffected()
+	if err != nil {
+		return report, err
+	}
+	report.Focus = int(focus)
+
+	if err := tx.Commit(); err != nil {
+		return report, err
 	}
 
-	log.Printf("已清理 %d 筆未完成的活動記錄", count)
-	return nil
+	return report, nil
 }
 
 // ... 實現其他方法 ...
+
+// loadHeartbeats 回傳各類型最後寫入的心跳
+func loadHeartbeats(ctx context.Context, tx *sql.Tx) (map[domain.ActivityType]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT activity_type, last_seen FROM activity_heartbeats`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	heartbeats := make(map[domain.ActivityType]int64)
+	for rows.Next() {
+		var activityType domain.ActivityType
+		var lastSeen int64
+		if err := rows.Scan(&activityType, &lastSeen); err != nil {
+			return nil, err
+		}
+		heartbeats[activityType] = lastSeen
+	}
+	return heartbeats, rows.Err()
+}
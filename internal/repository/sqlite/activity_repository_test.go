@@ -0,0 +1,107 @@
+package sqlite_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+)
+
+// TestGetActivitiesBetweenFindsLongAndOpenActivities 確認查詢只從最早可能重疊的活動開始讀取時，
+// 開始得很早的長時間活動與仍在進行的活動都不會漏掉
+func TestGetActivitiesBetweenFindsLongAndOpenActivities(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	_, repo := newTestRepo(t, day.Add(12*time.Hour))
+	ctx := context.Background()
+
+	check := func(name string, want ...int64) {
+		t.Helper()
+
+		var got []int64
+		for activity, err := range repo.GetActivitiesBetween(ctx, day.Add(10*time.Hour), day.Add(11*time.Hour)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, activity.ID)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: 與 10:00-11:00 重疊的活動 = %v，應為 %v", name, got, want)
+		}
+	}
+
+	long := saveActivity(t, repo, domain.KeyboardActivity, day.AddDate(0, 0, -4), day.Add(10*time.Hour+30*time.Minute))
+	saveActivity(t, repo, domain.MouseActivity, day.AddDate(0, 0, -3), day.AddDate(0, 0, -3).Add(time.Hour))
+	inside := saveActivity(t, repo, domain.ManualActivity, day.Add(10*time.Hour+15*time.Minute), day.Add(10*time.Hour+45*time.Minute))
+	saveActivity(t, repo, domain.ManualActivity, day.Add(11*time.Hour), day.Add(12*time.Hour))
+	check("已結束的活動", long.ID, inside.ID)
+
+	// 進行中的活動比最長的已結束活動還早開始
+	open := domain.Activity{Type: domain.MouseActivity}
+	open.SetStartTime(day.AddDate(0, 0, -6))
+	open, err := repo.Save(ctx, open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("加入進行中的活動", open.ID, long.ID, inside.ID)
+}
+
+// TestRecoverUnfinishedActivitiesUsesHeartbeatOfEachType 確認異常結束後每種類型的活動以自己的心跳結束，
+// 沒有該類型心跳時才使用整體的心跳
+func TestRecoverUnfinishedActivitiesUsesHeartbeatOfEachType(t *testing.T) {
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	db, repo := newTestRepo(t, start.Add(time.Hour))
+	ctx := context.Background()
+
+	open := make(map[domain.ActivityType]int64)
+	for _, activityType := range []domain.ActivityType{domain.MouseActivity, domain.KeyboardActivity, domain.ManualActivity} {
+		activity := domain.Activity{Type: activityType}
+		activity.SetStartTime(start)
+		activity, err := repo.Save(ctx, activity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		open[activityType] = activity.ID
+	}
+
+	// 鍵盤在 09:05 之後就沒有輸入，滑鼠持續到 09:20。
+	// 手動活動沒有自己的心跳，如同升級前只有整體心跳的資料庫
+	if err := repo.SaveHeartbeat(ctx, map[domain.ActivityType]int64{
+		domain.MouseActivity:    start.Add(10 * time.Minute).Unix(),
+		domain.KeyboardActivity: start.Add(5 * time.Minute).Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveHeartbeat(ctx, map[domain.ActivityType]int64{
+		domain.MouseActivity: start.Add(20 * time.Minute).Unix(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := repo.RecoverUnfinishedActivities(ctx, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := start.Add(20 * time.Minute); !report.Heartbeat.Equal(want) {
+		t.Errorf("整體的心跳 = %v，應為 %v", report.Heartbeat, want)
+	}
+
+	want := map[domain.ActivityType]time.Time{
+		domain.MouseActivity:    start.Add(20 * time.Minute),
+		domain.KeyboardActivity: start.Add(5 * time.Minute),
+		domain.ManualActivity:   start.Add(20 * time.Minute),
+	}
+	if len(report.Recovered) != len(want) {
+		t.Fatalf("復原 %d 筆活動，應為 %d 筆: %+v", len(report.Recovered), len(want), report.Recovered)
+	}
+	for _, recovered := range report.Recovered {
+		activity := recovered.Activity
+		if activity.ID != open[activity.Type] {
+			t.Errorf("復原的活動 %+v 不是未完成的活動", activity)
+		}
+		if !activity.EndTime().Equal(want[activity.Type]) || recovered.Source != domain.RecoveryFromHeartbeat {
+			t.Errorf("%v 活動以 %v 在 %v 結束，應以心跳在 %v 結束", activity.Type, recovered.Source, activity.EndTime(), want[activity.Type])
+		}
+	}
+	checkRollupsMatchRebuild(t, db, repo)
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+var _ repository.AuditRepository = &SQLiteAuditRepository{}
+
+type SQLiteAuditRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteAuditRepository(db *sql.DB) *SQLiteAuditRepository {
+	return &SQLiteAuditRepository{db: db}
+}
+
+// writeAudit 在 tx 中寫入一筆稽核記錄，讓記錄與變更一起提交或復原
+func writeAudit(ctx context.Context, tx *sql.Tx, entry domain.AuditEntry) error {
+	before, err := marshalAuditActivity(entry.Before, entry.Tags)
+	if err != nil {
+		return err
+	}
+	after, err := marshalAuditActivity(entry.After, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (created_at, source, operation, activity_id, before_value, after_value, detail)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, entry.Time.Unix(), entry.Source, entry.Operation, nullableID(entry.ActivityID), before, after, entry.Detail); err != nil {
+		log.Printf("寫入稽核記錄失敗: %v", err)
+		return err
+	}
+	return nil
+}
+
+// auditActivity 是稽核記錄中活動的 JSON，刪除時另外保存活動的標籤
+type auditActivity struct {
+	domain.Activity
+	Tags []int64 `json:",omitempty"`
+}
+
+func marshalAuditActivity(activity *domain.Activity, tags []int64) (interface{}, error) {
+	if activity == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(auditActivity{Activity: *activity, Tags: tags})
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func unmarshalAuditActivity(value sql.NullString) (*domain.Activity, []int64, error) {
+	if !value.Valid {
+		return nil, nil, nil
+	}
+	var activity auditActivity
+	if err := json.Unmarshal([]byte(value.String), &activity); err != nil {
+		return nil, nil, err
+	}
+	return &activity.Activity, activity.Tags, nil
+}
+
+func (r *SQLiteAuditRepository) GetAuditLog(ctx context.Context, from, to time.Time) ([]domain.AuditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, created_at, source, operation, activity_id, before_value, after_value, detail
+		FROM audit_log
+		WHERE created_at >= ? AND created_at < ?
+		ORDER BY created_at ASC, id ASC
+	`, from.Unix(), to.Unix())
+	if err != nil {
+		log.Printf("查詢稽核記錄失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []domain.AuditEntry
+	for rows.Next() {
+		var entry domain.AuditEntry
+		var createdAt int64
+		var activityID sql.NullInt64
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &createdAt, &entry.Source, &entry.Operation, &activityID, &before, &after, &entry.Detail); err != nil {
+			return nil, err
+		}
+		entry.Time = time.Unix(createdAt, 0)
+		entry.ActivityID = activityID.Int64
+		if entry.Before, entry.Tags, err = unmarshalAuditActivity(before); err != nil {
+			return nil, err
+		}
+		if entry.After, _, err = unmarshalAuditActivity(after); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
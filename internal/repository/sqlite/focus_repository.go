@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+func (r *SQLiteActivityRepository) StartFocus(ctx context.Context, focus domain.Focus, at time.Time) (domain.FocusPeriod, error) {
+	period := domain.FocusPeriod{Focus: focus, Start: at}
+
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO focus_periods (start_time, end_time, app, title)
+		VALUES (?, NULL, ?, ?)
+	`, at.Unix(), focus.App, focus.Title)
+	if err != nil {
+		log.Printf("保存焦點時段失敗: %v", err)
+		return period, err
+	}
+
+	period.ID, err = result.LastInsertId()
+	return period, err
+}
+
+func (r *SQLiteActivityRepository) EndFocus(ctx context.Context, id int64, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE focus_periods
+		SET end_time = MAX(start_time, ?)
+		WHERE id = ?
+	`, at.Unix(), id)
+	if err != nil {
+		log.Printf("更新焦點時段失敗: %v", err)
+		return err
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: 焦點時段 ID=%d", repository.ErrActivityNotFound, id)
+	}
+	return nil
+}
+
+func (r *SQLiteActivityRepository) GetFocusBetween(ctx context.Context, from, to time.Time) ([]domain.FocusPeriod, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, start_time, end_time, app, title
+		FROM focus_periods
+		WHERE start_time < ? AND (end_time IS NULL OR end_time > ?)
+		ORDER BY start_time ASC, id ASC
+	`, to.Unix(), from.Unix())
+	if err != nil {
+		log.Printf("查詢焦點時段失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var periods []domain.FocusPeriod
+	for rows.Next() {
+		var period domain.FocusPeriod
+		var start int64
+		var end sql.NullInt64
+		if err := rows.Scan(&period.ID, &start, &end, &period.App, &period.Title); err != nil {
+			return nil, err
+		}
+		period.Start = time.Unix(start, 0)
+		if end.Valid {
+			period.End = time.Unix(end.Int64, 0)
+		}
+		periods = append(periods, period)
+	}
+	return periods, rows.Err()
+}
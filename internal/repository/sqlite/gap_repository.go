@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"main/internal/domain"
+)
+
+func (r *SQLiteActivityRepository) SaveGap(ctx context.Context, gap domain.Gap) (domain.Gap, error) {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO gaps (start_time, end_time, reason)
+		VALUES (?, ?, ?)
+	`, gap.Start.Unix(), gap.End.Unix(), gap.Reason)
+	if err != nil {
+		log.Printf("保存休眠時段失敗: %v", err)
+		return gap, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return gap, err
+	}
+	gap.ID = id
+	return gap, nil
+}
+
+func (r *SQLiteActivityRepository) GetGapsBetween(ctx context.Context, from, to time.Time) ([]domain.Gap, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, start_time, end_time, reason
+		FROM gaps
+		WHERE start_time < ? AND end_time > ?
+		ORDER BY start_time ASC, id ASC
+	`, to.Unix(), from.Unix())
+	if err != nil {
+		log.Printf("查詢休眠時段失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var gaps []domain.Gap
+	for rows.Next() {
+		var gap domain.Gap
+		var start, end int64
+		if err := rows.Scan(&gap.ID, &start, &end, &gap.Reason); err != nil {
+			return nil, err
+		}
+		gap.Start = time.Unix(start, 0)
+		gap.End = time.Unix(end, 0)
+		gaps = append(gaps, gap)
+	}
+	return gaps, rows.Err()
+}
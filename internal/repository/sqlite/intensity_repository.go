@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+var _ repository.IntensityRepository = &SQLiteIntensityRepository{}
+
+// minuteSeconds 是輸入強度的桶寬，桶以 Unix 時間對齊整分
+const minuteSeconds = 60
+
+type SQLiteIntensityRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteIntensityRepository(db *sql.DB) *SQLiteIntensityRepository {
+	return &SQLiteIntensityRepository{db: db}
+}
+
+func (r *SQLiteIntensityRepository) AddIntensity(ctx context.Context, counters []domain.InputIntensity) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, counter := range counters {
+		if counter.IsZero() {
+			continue
+		}
+
+		minute := counter.Minute.Unix()
+		minute -= minute % minuteSeconds
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO input_intensity (minute_start, key_presses, clicks, scrolls, mouse_distance)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(minute_start) DO UPDATE SET
+				key_presses = key_presses + excluded.key_presses,
+				clicks = clicks + excluded.clicks,
+				scrolls = scrolls + excluded.scrolls,
+				mouse_distance = mouse_distance + excluded.mouse_distance
+		`, minute, counter.KeyPresses, counter.Clicks, counter.Scrolls, counter.MouseDistance); err != nil {
+			log.Printf("寫入輸入強度失敗: %v", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SQLiteIntensityRepository) GetIntensity(ctx context.Context, from, to time.Time) ([]domain.InputIntensity, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT minute_start, key_presses, clicks, scrolls, mouse_distance
+		FROM input_intensity
+		WHERE minute_start >= ? AND minute_start < ?
+		ORDER BY minute_start ASC
+	`, from.Unix()-from.Unix()%minuteSeconds, to.Unix())
+	if err != nil {
+		log.Printf("查詢輸入強度失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.InputIntensity
+	for rows.Next() {
+		var minute int64
+		var counter domain.InputIntensity
+		if err := rows.Scan(&minute, &counter.KeyPresses, &counter.Clicks, &counter.Scrolls, &counter.MouseDistance); err != nil {
+			return nil, err
+		}
+		counter.Minute = time.Unix(minute, 0).In(from.Location())
+		result = append(result, counter)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,129 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrDatabaseTooNew 表示資料庫的 schema 版本比目前程式所知的還新
+var ErrDatabaseTooNew = errors.New("資料庫版本比程式新")
+
+// Migration 代表一個只能往前執行的 schema 變更
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, tx *sql.Tx) error
+}
+
+// Exec 回傳依序執行多個 SQL 敘述的 Up 函式
+func Exec(statements ...string) func(ctx context.Context, tx *sql.Tx) error {
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// LatestVersion 回傳目前程式支援的最新 schema 版本
+func LatestVersion() int {
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+// CurrentVersion 回傳資料庫目前的 schema 版本，未曾遷移的資料庫回傳 0
+func CurrentVersion(ctx context.Context, db *sql.DB) (int, error) {
+	if err := ensureVersionTable(ctx, db); err != nil {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// Migrate 將資料庫升級到最新版本，每個遷移在各自的交易中執行
+func Migrate(ctx context.Context, db *sql.DB) error {
+	if err := validate(all); err != nil {
+		return err
+	}
+
+	current, err := CurrentVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("無法讀取資料庫版本: %v", err)
+	}
+
+	latest := LatestVersion()
+	if current > latest {
+		return fmt.Errorf("%w: 資料庫版本=%d, 程式支援版本=%d", ErrDatabaseTooNew, current, latest)
+	}
+
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+
+		log.Printf("執行資料庫遷移: 版本=%d, 說明=%s", m.Version, m.Description)
+		if err := apply(ctx, db, m); err != nil {
+			return fmt.Errorf("資料庫遷移 %d 失敗: %v", m.Version, err)
+		}
+	}
+
+	log.Printf("資料庫版本: %d", latest)
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := m.Up(ctx, tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO schema_version (version, description, applied_at)
+		VALUES (?, ?, ?)
+	`, m.Version, m.Description, time.Now().Unix()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ensureVersionTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at INTEGER NOT NULL  -- Unix timestamp in seconds
+		)
+	`)
+	return err
+}
+
+// validate 確保遷移版本從 1 開始連續遞增
+func validate(migrations []Migration) error {
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return fmt.Errorf("遷移版本不連續: 位置=%d, 版本=%d", i, m.Version)
+		}
+		if m.Up == nil {
+			return fmt.Errorf("遷移 %d 缺少 Up 函式", m.Version)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"main/internal/repository/sqlite/migrations"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// TestMigrateRemovesDuplicatesFromBaselineSchema 從遷移前程式建立的活動表升級，
+// 確認每個開始時間與類型只保留 ID 最小的一筆，且資料庫升級到最新版本
+func TestMigrateRemovesDuplicatesFromBaselineSchema(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	// 遷移前的程式啟動時建立的活動表，沒有 schema_version
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS activities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			start_time INTEGER NOT NULL,  -- Unix timestamp in seconds
+			end_time INTEGER,            -- Unix timestamp in seconds, NULL means not ended
+			activity_type TEXT NOT NULL
+		)
+	`); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []struct {
+		id         int64
+		start, end int64
+		typ        string
+	}{
+		{1, 1000, 1060, "mouse"},
+		{2, 1000, 1030, "keyboard"},
+		{3, 1000, 1060, "mouse"},
+		{4, 2000, 2120, "mouse"},
+		{5, 1000, 1030, "keyboard"},
+		{6, 2000, 2120, "mouse"},
+		{7, 1000, 1060, "mouse"},
+	} {
+		if _, err := db.Exec(`INSERT INTO activities (id, start_time, end_time, activity_type) VALUES (?, ?, ?, ?)`,
+			row.id, row.start, row.end, row.typ); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrations.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := migrations.CurrentVersion(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != migrations.LatestVersion() {
+		t.Errorf("遷移後的版本 = %d，應為 %d", version, migrations.LatestVersion())
+	}
+
+	rows, err := db.Query(`SELECT id FROM activities ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{1, 2, 4}; !slices.Equal(ids, want) {
+		t.Errorf("保留的活動 = %v，應為 %v", ids, want)
+	}
+
+	// 重複的記錄在回填彙總之前就已刪除，不會重複計算
+	var mouseSeconds int64
+	if err := db.QueryRow(`SELECT COALESCE(SUM(seconds), 0) FROM activity_rollup_hourly WHERE activity_type = 'mouse'`).Scan(&mouseSeconds); err != nil {
+		t.Fatal(err)
+	}
+	if mouseSeconds != 180 {
+		t.Errorf("滑鼠的每小時彙總 = %d 秒，應為 180 秒", mouseSeconds)
+	}
+
+	if _, err := db.Exec(`INSERT INTO activities (start_time, end_time, activity_type) VALUES (1000, 1060, 'mouse')`); err == nil {
+		t.Error("遷移後仍可新增開始時間與類型重複的活動")
+	}
+
+	// 已是最新版本時再次遷移不做任何變更
+	if err := migrations.Migrate(ctx, db); err != nil {
+		t.Fatalf("再次遷移: %v", err)
+	}
+	if version, err := migrations.CurrentVersion(ctx, db); err != nil || version != migrations.LatestVersion() {
+		t.Errorf("再次遷移後的版本 = %d (%v)，應為 %d", version, err, migrations.LatestVersion())
+	}
+}
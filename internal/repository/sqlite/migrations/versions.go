@@ -0,0 +1,241 @@
+package migrations
+
+// all 依版本排序列出所有遷移，只能在尾端新增，不可修改已發布的項目
+var all = []Migration{
+	{
+		Version:     1,
+		Description: "建立活動記錄表",
+		Up: Exec(`
+			CREATE TABLE IF NOT EXISTS activities (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				start_time INTEGER NOT NULL,  -- Unix timestamp in seconds
+				end_time INTEGER,            -- Unix timestamp in seconds, NULL means not ended
+				activity_type TEXT NOT NULL
+			)
+		`),
+	},
+	{
+		Version:     2,
+		Description: "建立心跳記錄表",
+		Up: Exec(`
+			CREATE TABLE IF NOT EXISTS heartbeat (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				last_seen INTEGER NOT NULL  -- Unix timestamp in seconds
+			)
+		`),
+	},
+	{
+		Version:     3,
+		Description: "活動記錄的開始時間與類型不可重複",
+		Up: Exec(
+			// 舊版以開始時間與類型更新活動，重複的記錄內容相同，只保留最早的一筆
+			`DELETE FROM activities
+			WHERE id NOT IN (
+				SELECT MIN(id) FROM activities GROUP BY start_time, activity_type
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_start_type
+			ON activities (start_time, activity_type)`,
+		),
+	},
+	{
+		Version:     4,
+		Description: "依開始時間查詢活動的索引",
+		Up: Exec(`
+			CREATE INDEX IF NOT EXISTS idx_activities_start_time
+			ON activities (start_time)
+		`),
+	},
+	{
+		Version:     5,
+		Description: "建立每小時與每日的活動彙總表",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS activity_rollup_hourly (
+				bucket_start INTEGER NOT NULL,  -- Unix timestamp in seconds, aligned to the hour
+				activity_type TEXT NOT NULL,
+				seconds INTEGER NOT NULL,
+				PRIMARY KEY (bucket_start, activity_type)
+			)`,
+			`CREATE TABLE IF NOT EXISTS activity_rollup_daily (
+				day_start INTEGER NOT NULL,  -- Unix timestamp in seconds, start of the day in rollup_state.calendar
+				activity_type TEXT NOT NULL,
+				seconds INTEGER NOT NULL,
+				PRIMARY KEY (day_start, activity_type)
+			)`,
+			`CREATE TABLE IF NOT EXISTS rollup_state (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				calendar TEXT NOT NULL  -- 每日彙總使用的時區與每日開始時間
+			)`,
+			// 回填既有活動的每小時彙總；每日彙總依設定的時區在啟動時建立
+			`WITH RECURSIVE buckets (activity_type, start_time, end_time, bucket_start) AS (
+				SELECT activity_type, start_time, end_time, start_time - start_time % 3600
+				FROM activities
+				WHERE end_time IS NOT NULL AND end_time > start_time
+				UNION ALL
+				SELECT activity_type, start_time, end_time, bucket_start + 3600
+				FROM buckets
+				WHERE bucket_start + 3600 < end_time
+			)
+			INSERT INTO activity_rollup_hourly (bucket_start, activity_type, seconds)
+			SELECT bucket_start, activity_type,
+				SUM(MIN(end_time, bucket_start + 3600) - MAX(start_time, bucket_start))
+			FROM buckets
+			GROUP BY bucket_start, activity_type`,
+		),
+	},
+	{
+		Version:     6,
+		Description: "彙總表記錄滑鼠與鍵盤的重疊時間",
+		Up: Exec(
+			// 回填每小時彙總的重疊時間；清除彙總狀態讓每日彙總在啟動時重新建立
+			`WITH RECURSIVE overlaps (start_time, end_time) AS (
+				SELECT MAX(a.start_time, b.start_time), MIN(a.end_time, b.end_time)
+				FROM activities a
+				JOIN activities b
+					ON a.activity_type < b.activity_type
+					AND a.start_time < b.end_time AND b.start_time < a.end_time
+				WHERE a.end_time IS NOT NULL AND a.end_time > a.start_time
+					AND b.end_time IS NOT NULL AND b.end_time > b.start_time
+			),
+			buckets (start_time, end_time, bucket_start) AS (
+				SELECT start_time, end_time, start_time - start_time % 3600
+				FROM overlaps
+				UNION ALL
+				SELECT start_time, end_time, bucket_start + 3600
+				FROM buckets
+				WHERE bucket_start + 3600 < end_time
+			)
+			INSERT INTO activity_rollup_hourly (bucket_start, activity_type, seconds)
+			SELECT bucket_start, 'overlap',
+				SUM(MIN(end_time, bucket_start + 3600) - MAX(start_time, bucket_start))
+			FROM buckets
+			GROUP BY bucket_start`,
+			`DELETE FROM rollup_state`,
+		),
+	},
+	{
+		Version:     7,
+		Description: "建立每分鐘輸入強度表",
+		Up: Exec(`
+			CREATE TABLE IF NOT EXISTS input_intensity (
+				minute_start INTEGER PRIMARY KEY,  -- Unix timestamp in seconds, aligned to the minute
+				key_presses INTEGER NOT NULL DEFAULT 0,
+				clicks INTEGER NOT NULL DEFAULT 0,
+				scrolls INTEGER NOT NULL DEFAULT 0,
+				mouse_distance REAL NOT NULL DEFAULT 0  -- pixels
+			)
+		`),
+	},
+	{
+		Version:     8,
+		Description: "建立休眠等無法記錄活動的時段表",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS gaps (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				start_time INTEGER NOT NULL,  -- Unix timestamp in seconds
+				end_time INTEGER NOT NULL,    -- Unix timestamp in seconds
+				reason TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_gaps_start_time ON gaps (start_time)`,
+		),
+	},
+	{
+		Version:     9,
+		Description: "建立取得焦點的應用程式與視窗時段表",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS focus_periods (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				start_time INTEGER NOT NULL,  -- Unix timestamp in seconds
+				end_time INTEGER,             -- Unix timestamp in seconds, NULL means still focused
+				app TEXT NOT NULL,
+				title TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_focus_periods_start_time ON focus_periods (start_time)`,
+		),
+	},
+	{
+		Version:     10,
+		Description: "建立專案表，活動可由規則歸類到一個專案",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS projects (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE
+			)`,
+			`ALTER TABLE activities ADD COLUMN project_id INTEGER REFERENCES projects (id)`,
+			`CREATE INDEX IF NOT EXISTS idx_activities_project_id ON activities (project_id)`,
+		),
+	},
+	{
+		Version:     11,
+		Description: "建立標籤表，活動可有多個標籤",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name TEXT NOT NULL UNIQUE
+			)`,
+			`CREATE TABLE IF NOT EXISTS activity_tags (
+				activity_id INTEGER NOT NULL REFERENCES activities (id),
+				tag_id INTEGER NOT NULL REFERENCES tags (id),
+				PRIMARY KEY (activity_id, tag_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_activity_tags_tag_id ON activity_tags (tag_id)`,
+		),
+	},
+	{
+		Version:     12,
+		Description: "標示手動新增或修改過的活動",
+		Up: Exec(
+			`ALTER TABLE activities ADD COLUMN manual INTEGER NOT NULL DEFAULT 0`,
+		),
+	},
+	{
+		Version:     13,
+		Description: "建立只能新增的活動變更稽核記錄表",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				created_at INTEGER NOT NULL,  -- Unix timestamp in seconds
+				source TEXT NOT NULL,
+				operation TEXT NOT NULL,
+				activity_id INTEGER,          -- NULL 表示批次操作
+				before_value TEXT,            -- 變更前的活動（JSON），NULL 表示新增
+				after_value TEXT,             -- 變更後的活動（JSON），NULL 表示刪除
+				detail TEXT NOT NULL DEFAULT ''
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at)`,
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+			BEGIN
+				SELECT RAISE(ABORT, 'audit_log 只能新增');
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+			BEGIN
+				SELECT RAISE(ABORT, 'audit_log 只能新增');
+			END`,
+		),
+	},
+	{
+		Version:     14,
+		Description: "建立活動與焦點時段長度的索引，統計查詢不必掃描整個表找出最長的時段",
+		Up: Exec(
+			`CREATE INDEX IF NOT EXISTS idx_activities_duration ON activities (end_time - start_time)`,
+			`CREATE INDEX IF NOT EXISTS idx_focus_periods_duration ON focus_periods (end_time - start_time)`,
+			`CREATE INDEX IF NOT EXISTS idx_focus_periods_open ON focus_periods (start_time) WHERE end_time IS NULL`,
+		),
+	},
+	{
+		Version:     15,
+		Description: "建立進行中活動的索引，查詢時間範圍時能快速找到最早仍在進行的活動",
+		Up: Exec(
+			`CREATE INDEX IF NOT EXISTS idx_activities_open ON activities (start_time) WHERE end_time IS NULL OR end_time = 0`,
+		),
+	},
+	{
+		Version:     16,
+		Description: "建立各活動類型的心跳表，異常結束後每種類型的活動以自己最後的輸入時間結束",
+		Up: Exec(
+			`CREATE TABLE IF NOT EXISTS activity_heartbeats (
+				activity_type TEXT PRIMARY KEY,
+				last_seen INTEGER NOT NULL  -- Unix timestamp in seconds
+			)`,
+		),
+	},
+}
@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"main/internal/domain"
+	"main/internal/repository"
+	"main/pkg/clock"
+)
+
+var _ repository.ProjectRepository = &SQLiteProjectRepository{}
+
+type SQLiteProjectRepository struct {
+	db    *sql.DB
+	clock clock.Clock
+}
+
+func NewSQLiteProjectRepository(db *sql.DB, clk clock.Clock) *SQLiteProjectRepository {
+	return &SQLiteProjectRepository{db: db, clock: clk}
+}
+
+func (r *SQLiteProjectRepository) CreateProject(ctx context.Context, name string) (domain.Project, error) {
+	project := domain.Project{Name: name}
+
+	result, err := r.db.ExecContext(ctx, `INSERT INTO projects (name) VALUES (?)`, name)
+	if err != nil {
+		log.Printf("新增專案失敗: %v", err)
+		return project, err
+	}
+
+	project.ID, err = result.LastInsertId()
+	return project, err
+}
+
+func (r *SQLiteProjectRepository) GetProject(ctx context.Context, id int64) (domain.Project, error) {
+	project := domain.Project{ID: id}
+
+	err := r.db.QueryRowContext(ctx, `SELECT name FROM projects WHERE id = ?`, id).Scan(&project.Name)
+	if err == sql.ErrNoRows {
+		return project, fmt.Errorf("%w: ID=%d", repository.ErrProjectNotFound, id)
+	}
+	return project, err
+}
+
+func (r *SQLiteProjectRepository) GetProjects(ctx context.Context) ([]domain.Project, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name FROM projects ORDER BY name ASC`)
+	if err != nil {
+		log.Printf("查詢專案失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []domain.Project
+	for rows.Next() {
+		var project domain.Project
+		if err := rows.Scan(&project.ID, &project.Name); err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (r *SQLiteProjectRepository) CreateTag(ctx context.Context, name string) (domain.Tag, error) {
+	tag := domain.Tag{Name: name}
+
+	result, err := r.db.ExecContext(ctx, `INSERT INTO tags (name) VALUES (?)`, name)
+	if err != nil {
+		log.Printf("新增標籤失敗: %v", err)
+		return tag, err
+	}
+
+	tag.ID, err = result.LastInsertId()
+	return tag, err
+}
+
+func (r *SQLiteProjectRepository) GetTags(ctx context.Context) ([]domain.Tag, error) {
+	return r.queryTags(ctx, `SELECT id, name FROM tags ORDER BY name ASC`)
+}
+
+func (r *SQLiteProjectRepository) SetActivityTags(ctx context.Context, activityID int64, tagIDs []int64, source domain.AuditSource) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM activities WHERE id = ?)`, activityID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: ID=%d", repository.ErrActivityNotFound, activityID)
+	}
+
+	previous, err := activityTagIDs(ctx, tx, activityID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activity_tags WHERE activity_id = ?`, activityID); err != nil {
+		log.Printf("清除活動標籤失敗: %v", err)
+		return err
+	}
+	for _, tagID := range tagIDs {
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM tags WHERE id = ?)`, tagID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: ID=%d", repository.ErrTagNotFound, tagID)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO activity_tags (activity_id, tag_id)
+			VALUES (?, ?)
+		`, activityID, tagID); err != nil {
+			log.Printf("保存活動標籤失敗: %v", err)
+			return err
+		}
+	}
+
+	if err := writeAudit(ctx, tx, domain.AuditEntry{
+		Time:       r.clock.Now(),
+		Source:     source,
+		Operation:  domain.AuditUpdate,
+		ActivityID: activityID,
+		Detail:     fmt.Sprintf("標籤 %v -> %v", previous, tagIDs),
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// activityTagIDs 依 ID 順序回傳活動的標籤 ID
+func activityTagIDs(ctx context.Context, tx *sql.Tx, activityID int64) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT tag_id FROM activity_tags WHERE activity_id = ? ORDER BY tag_id`, activityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tagIDs []int64
+	for rows.Next() {
+		var tagID int64
+		if err := rows.Scan(&tagID); err != nil {
+			return nil, err
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+	return tagIDs, rows.Err()
+}
+
+func (r *SQLiteProjectRepository) GetActivityTags(ctx context.Context, activityID int64) ([]domain.Tag, error) {
+	return r.queryTags(ctx, `
+		SELECT t.id, t.name
+		FROM activity_tags at
+		JOIN tags t ON t.id = at.tag_id
+		WHERE at.activity_id = ?
+		ORDER BY t.name ASC
+	`, activityID)
+}
+
+func (r *SQLiteProjectRepository) queryTags(ctx context.Context, query string, args ...interface{}) ([]domain.Tag, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Printf("查詢標籤失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []domain.Tag
+	for rows.Next() {
+		var tag domain.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
@@ -0,0 +1,277 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"main/internal/domain"
+)
+
+// mergeGroup 是一組同一個工作階段內、會合併成一筆的記錄，保留第一筆並刪除其餘
+type mergeGroup struct {
+	merged    domain.Activity
+	originals []domain.Activity
+}
+
+// ApplyRetention 先刪除 PurgeBefore 之前結束的活動與輸入強度等明細，
+// 再將 CompactBefore 之前結束、間隔不超過 MergeGap 的同類型同專案活動合併成一筆。
+// 合併後的記錄從第一筆開始到最後一筆結束，與工作階段一樣把記錄之間的短暫間隔算入；
+// 刪除與合併都逐筆調整彙總，整次執行只寫入一筆稽核記錄，所有變更在同一個交易中完成。
+func (r *SQLiteActivityRepository) ApplyRetention(ctx context.Context, plan domain.RetentionPlan, dryRun bool) (domain.RetentionReport, error) {
+	report := domain.RetentionReport{DryRun: dryRun, Plan: plan}
+
+	tx, delta, done, err := r.beginRollupTx(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer done()
+
+	var purged []domain.Activity
+	var groups []mergeGroup
+
+	if !plan.PurgeBefore.IsZero() {
+		purgeBefore := plan.PurgeBefore.Unix()
+
+		purged, err = queryActivities(ctx, tx, `
+			SELECT id, start_time, end_time, activity_type, project_id, manual
+			FROM activities
+			WHERE end_time IS NOT NULL AND end_time > 0 AND end_time <= ?
+			ORDER BY start_time ASC, id ASC
+		`, purgeBefore)
+		if err != nil {
+			log.Printf("查詢舊活動失敗: %v", err)
+			return report, err
+		}
+
+		// 逐筆從彙總扣除，跨過界線而保留的活動在界線前的彙總不受影響
+		for _, activity := range purged {
+			if err := delta.add(ctx, tx, activity, -1); err != nil {
+				log.Printf("計算活動彙總失敗: %v", err)
+				return report, err
+			}
+			if err := deleteActivityRow(ctx, tx, activity.ID); err != nil {
+				log.Printf("刪除舊活動失敗: %v", err)
+				return report, err
+			}
+		}
+		report.PurgedActivities = len(purged)
+
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM input_intensity WHERE minute_start + 60 <= ?
+		`, purgeBefore); err != nil {
+			return report, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM gaps WHERE end_time <= ?
+		`, purgeBefore); err != nil {
+			return report, err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM focus_periods WHERE end_time IS NOT NULL AND end_time <= ?
+		`, purgeBefore); err != nil {
+			return report, err
+		}
+	}
+
+	if !plan.CompactBefore.IsZero() {
+		var scanned int
+		groups, scanned, err = findMergeGroups(ctx, tx, plan)
+		if err != nil {
+			log.Printf("查詢可合併的活動失敗: %v", err)
+			return report, err
+		}
+		report.ScannedRows = scanned
+
+		for _, group := range groups {
+			for _, original := range group.originals {
+				if err := delta.add(ctx, tx, original, -1); err != nil {
+					return report, err
+				}
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE activities SET end_time = ? WHERE id = ?
+			`, group.merged.EndTimeUnix, group.merged.ID); err != nil {
+				return report, err
+			}
+
+			for _, original := range group.originals {
+				if original.ID == group.merged.ID {
+					continue
+				}
+				// 合併後保留所有原本記錄的標籤
+				if _, err := tx.ExecContext(ctx, `
+					INSERT OR IGNORE INTO activity_tags (activity_id, tag_id)
+					SELECT ?, tag_id FROM activity_tags WHERE activity_id = ?
+				`, group.merged.ID, original.ID); err != nil {
+					return report, err
+				}
+				if err := deleteActivityRow(ctx, tx, original.ID); err != nil {
+					return report, err
+				}
+				report.MergedRows++
+			}
+			if err := delta.add(ctx, tx, group.merged, 1); err != nil {
+				return report, err
+			}
+			report.Sessions++
+		}
+	}
+
+	if err := delta.apply(ctx, tx); err != nil {
+		log.Printf("更新活動彙總失敗: %v", err)
+		return report, err
+	}
+
+	// 每筆活動各寫一份快照會讓資料庫隨保存政策不斷變大，整次執行只記錄界線、數量與影響的範圍
+	if len(purged) > 0 || len(groups) > 0 {
+		if err := writeAudit(ctx, tx, domain.AuditEntry{
+			Time:      r.clock.Now(),
+			Source:    domain.AuditRetention,
+			Operation: domain.AuditBulk,
+			Detail:    retentionDetail(plan, purged, groups),
+		}); err != nil {
+			log.Printf("寫入保存政策稽核記錄失敗: %v", err)
+			return report, err
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	return report, tx.Commit()
+}
+
+// queryActivities 在交易中讀取查詢到的所有活動，讀完後才能在同一個交易中修改
+func queryActivities(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]domain.Activity, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activities []domain.Activity
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+// retentionDetail 描述一次保存政策的界線、刪除與合併的數量，以及受影響活動的 ID 與時間範圍
+func retentionDetail(plan domain.RetentionPlan, purged []domain.Activity, groups []mergeGroup) string {
+	var parts []string
+	if len(purged) > 0 {
+		parts = append(parts, fmt.Sprintf("刪除 %s 之前結束的活動 %d 筆，ID %s，時間 %s",
+			plan.PurgeBefore.Format(time.DateTime), len(purged), idRanges(purged), timeRange(purged, plan.PurgeBefore.Location())))
+	}
+	if len(groups) > 0 {
+		var kept, merged []domain.Activity
+		for _, group := range groups {
+			kept = append(kept, group.merged)
+			merged = append(merged, group.originals[1:]...)
+		}
+		parts = append(parts, fmt.Sprintf("合併 %s 之前結束、間隔不超過 %v 的活動：刪除 %d 筆，ID %s，併入 %d 筆，ID %s，時間 %s",
+			plan.CompactBefore.Format(time.DateTime), plan.MergeGap,
+			len(merged), idRanges(merged), len(kept), idRanges(kept), timeRange(kept, plan.CompactBefore.Location())))
+	}
+	return strings.Join(parts, "；")
+}
+
+// idRanges 將活動的 ID 排序後把連續的 ID 寫成範圍，例如 "1-5, 8"
+func idRanges(activities []domain.Activity) string {
+	ids := make([]int64, len(activities))
+	for i, activity := range activities {
+		ids[i] = activity.ID
+	}
+	slices.Sort(ids)
+
+	var ranges []string
+	for i := 0; i < len(ids); {
+		j := i
+		for j+1 < len(ids) && ids[j+1] == ids[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, strconv.FormatInt(ids[i], 10))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", ids[i], ids[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// timeRange 以 loc 的時間回傳活動最早的開始時間到最晚的結束時間
+func timeRange(activities []domain.Activity, loc *time.Location) string {
+	start, end := activities[0].StartTimeUnix, activities[0].EndTimeUnix
+	for _, activity := range activities[1:] {
+		start = min(start, activity.StartTimeUnix)
+		end = max(end, activity.EndTimeUnix)
+	}
+	return fmt.Sprintf("%s 至 %s",
+		time.Unix(start, 0).In(loc).Format(time.DateTime), time.Unix(end, 0).In(loc).Format(time.DateTime))
+}
+
+// deleteActivityRow 刪除活動與它的標籤，呼叫前需先從彙總扣除
+func deleteActivityRow(ctx context.Context, tx *sql.Tx, id int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activity_tags WHERE activity_id = ?`, id); err != nil {
+		log.Printf("刪除活動標籤失敗: %v", err)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activities WHERE id = ?`, id); err != nil {
+		log.Printf("刪除活動失敗: %v", err)
+		return err
+	}
+	return nil
+}
+
+func findMergeGroups(ctx context.Context, tx *sql.Tx, plan domain.RetentionPlan) ([]mergeGroup, int, error) {
+	activities, err := queryActivities(ctx, tx, `
+		SELECT id, start_time, end_time, activity_type, project_id, manual
+		FROM activities
+		WHERE end_time IS NOT NULL AND end_time > 0 AND end_time <= ?
+		ORDER BY activity_type ASC, COALESCE(project_id, 0) ASC, manual ASC, start_time ASC, id ASC
+	`, plan.CompactBefore.Unix())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	gap := int64(plan.MergeGap / time.Second)
+	var groups []mergeGroup
+	var current *mergeGroup
+	flush := func() {
+		if current != nil && len(current.originals) > 1 {
+			groups = append(groups, *current)
+		}
+		current = nil
+	}
+
+	for _, activity := range activities {
+		// 追蹤器的記錄在閾值到期後才開始下一筆，彼此之間一定有間隔，間隔不超過 gap 時視為同一個工作階段
+		if current != nil &&
+			current.merged.Type == activity.Type &&
+			current.merged.ProjectID == activity.ProjectID &&
+			current.merged.Manual == activity.Manual &&
+			activity.StartTimeUnix <= current.merged.EndTimeUnix+gap {
+			current.merged.EndTimeUnix = max(current.merged.EndTimeUnix, activity.EndTimeUnix)
+			current.originals = append(current.originals, activity)
+			continue
+		}
+
+		flush()
+		current = &mergeGroup{merged: activity, originals: []domain.Activity{activity}}
+	}
+	flush()
+
+	return groups, len(activities), nil
+}
@@ -0,0 +1,244 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"main/internal/domain"
+	"main/internal/repository/sqlite"
+	"main/internal/repository/sqlite/migrations"
+	"main/pkg/clock"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+func newTestRepo(t *testing.T, now time.Time) (*sql.DB, *sqlite.SQLiteActivityRepository) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if err := migrations.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	repo := sqlite.NewSQLiteActivityRepository(db, clock.NewFake(now))
+	if err := repo.UseCalendar(ctx, domain.NewCalendar(time.UTC, 0)); err != nil {
+		t.Fatal(err)
+	}
+	return db, repo
+}
+
+func saveActivity(t *testing.T, repo *sqlite.SQLiteActivityRepository, activityType domain.ActivityType, start, end time.Time) domain.Activity {
+	t.Helper()
+
+	activity := domain.Activity{Type: activityType}
+	activity.SetStartTime(start)
+	activity.SetEndTime(end)
+	saved, err := repo.Save(context.Background(), activity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+// rollups 回傳彙總表的內容，鍵為 "表:起點:類型"
+func rollups(t *testing.T, db *sql.DB) map[string]int64 {
+	t.Helper()
+
+	result := make(map[string]int64)
+	for _, table := range []struct{ name, column string }{
+		{"activity_rollup_hourly", "bucket_start"},
+		{"activity_rollup_daily", "day_start"},
+	} {
+		rows, err := db.Query(`SELECT ` + table.column + `, activity_type, seconds FROM ` + table.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var start, seconds int64
+			var activityType string
+			if err := rows.Scan(&start, &activityType, &seconds); err != nil {
+				t.Fatal(err)
+			}
+			result[table.name+":"+time.Unix(start, 0).UTC().Format(time.DateTime)+":"+activityType] = seconds
+		}
+		rows.Close()
+	}
+	return result
+}
+
+// checkRollupsMatchRebuild 確認逐筆調整的彙總與從原始記錄重建的結果相同
+func checkRollupsMatchRebuild(t *testing.T, db *sql.DB, repo *sqlite.SQLiteActivityRepository) map[string]int64 {
+	t.Helper()
+
+	incremental := rollups(t, db)
+	if err := repo.RebuildRollups(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt := rollups(t, db)
+	if !maps.Equal(incremental, rebuilt) {
+		t.Errorf("逐筆調整的彙總與重建的結果不同\n逐筆: %v\n重建: %v", incremental, rebuilt)
+	}
+	return rebuilt
+}
+
+func TestRetentionPurgeKeepsRollupsOfActivitiesCrossingTheBoundary(t *testing.T) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	db, repo := newTestRepo(t, day.AddDate(0, 6, 0))
+
+	saveActivity(t, repo, domain.MouseActivity, day.Add(9*time.Hour), day.Add(10*time.Hour))
+	saveActivity(t, repo, domain.KeyboardActivity, day.Add(9*time.Hour+30*time.Minute), day.Add(11*time.Hour))
+	// 從前一天晚上跨到界線之後，保留
+	saveActivity(t, repo, domain.MouseActivity, day.Add(23*time.Hour), day.Add(25*time.Hour))
+
+	plan := domain.RetentionPlan{PurgeBefore: day.AddDate(0, 0, 1)}
+	report, err := repo.ApplyRetention(context.Background(), plan, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.PurgedActivities != 2 {
+		t.Errorf("刪除 %d 筆，應為 2 筆", report.PurgedActivities)
+	}
+
+	got := checkRollupsMatchRebuild(t, db, repo)
+	if seconds := got["activity_rollup_daily:2026-01-10 00:00:00:mouse"]; seconds != 3600 {
+		t.Errorf("保留的活動在界線前一天的彙總 = %d 秒，應為 3600 秒", seconds)
+	}
+}
+
+// TestRetentionCompactionMergesRowsWithinSessionGap 以追蹤器產生的記錄合併：
+// 同類型的記錄之間一定有閾值造成的間隔，間隔不超過 MergeGap 的記錄合併成一筆
+func TestRetentionCompactionMergesRowsWithinSessionGap(t *testing.T) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute, second int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second)
+	}
+	db, repo := newTestRepo(t, day.AddDate(0, 1, 0))
+	ctx := context.Background()
+
+	// 滑鼠的前三筆間隔 50 與 80 秒，合併成 09:00-09:30；間隔 10 分鐘的第四筆保留
+	first := saveActivity(t, repo, domain.MouseActivity, at(9, 0, 0), at(9, 10, 0))
+	saveActivity(t, repo, domain.MouseActivity, at(9, 10, 50), at(9, 20, 0))
+	saveActivity(t, repo, domain.MouseActivity, at(9, 21, 20), at(9, 30, 0))
+	saveActivity(t, repo, domain.MouseActivity, at(9, 40, 0), at(9, 50, 0))
+	// 不同類型的鍵盤記錄不與滑鼠合併
+	saveActivity(t, repo, domain.KeyboardActivity, at(9, 5, 0), at(9, 15, 0))
+	// 手動記錄不與追蹤的記錄合併
+	manual := domain.Activity{Type: domain.ManualActivity, Manual: true}
+	manual.SetStartTime(at(10, 0, 0))
+	manual.SetEndTime(at(11, 0, 0))
+	if _, err := repo.Save(ctx, manual); err != nil {
+		t.Fatal(err)
+	}
+	// 界線之後才結束的記錄不合併
+	saveActivity(t, repo, domain.MouseActivity, at(23, 59, 0), at(24, 5, 0))
+
+	plan := domain.RetentionPlan{CompactBefore: day.AddDate(0, 0, 1), MergeGap: 2 * time.Minute}
+	report, err := repo.ApplyRetention(ctx, plan, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.MergedRows != 2 || report.Sessions != 1 {
+		t.Errorf("合併結果 = 刪除 %d 筆、合併成 %d 筆，應為 2 與 1", report.MergedRows, report.Sessions)
+	}
+
+	activities, err := repo.GetActivities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 5 {
+		t.Fatalf("合併後有 %d 筆活動，應為 5 筆: %+v", len(activities), activities)
+	}
+	for _, activity := range activities {
+		if activity.ID == first.ID && (!activity.StartTime().Equal(at(9, 0, 0)) || !activity.EndTime().Equal(at(9, 30, 0))) {
+			t.Errorf("合併後的記錄 = %v-%v，應為 09:00-09:30", activity.StartTime(), activity.EndTime())
+		}
+	}
+
+	// 合併後的滑鼠時間包含工作階段內的間隔
+	got := checkRollupsMatchRebuild(t, db, repo)
+	if seconds := got["activity_rollup_daily:2026-01-10 00:00:00:mouse"]; seconds != 40*60+60 {
+		t.Errorf("合併後當天的滑鼠時間 = %d 秒，應為 %d 秒", seconds, 40*60+60)
+	}
+}
+
+// retentionAudit 回傳保存政策寫入的稽核記錄
+func retentionAudit(t *testing.T, db *sql.DB) []domain.AuditEntry {
+	t.Helper()
+
+	entries, err := sqlite.NewSQLiteAuditRepository(db).GetAuditLog(context.Background(), time.Unix(0, 0), time.Unix(1<<40, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var retention []domain.AuditEntry
+	for _, entry := range entries {
+		if entry.Source == domain.AuditRetention {
+			retention = append(retention, entry)
+		}
+	}
+	return retention
+}
+
+// TestRetentionWritesOneAuditEntryPerRun 確認保存政策每次執行只寫入一筆稽核記錄，
+// 記錄界線、數量與受影響活動的 ID 範圍，沒有變更時不寫入
+func TestRetentionWritesOneAuditEntryPerRun(t *testing.T) {
+	day := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	db, repo := newTestRepo(t, day.AddDate(0, 6, 0))
+	ctx := context.Background()
+
+	// 第一天的三筆刪除，第二天間隔 30 秒的兩筆合併
+	saveActivity(t, repo, domain.MouseActivity, day.Add(9*time.Hour), day.Add(10*time.Hour))
+	saveActivity(t, repo, domain.KeyboardActivity, day.Add(9*time.Hour+30*time.Minute), day.Add(11*time.Hour))
+	saveActivity(t, repo, domain.MouseActivity, day.Add(13*time.Hour), day.Add(14*time.Hour))
+	next := day.AddDate(0, 0, 1)
+	saveActivity(t, repo, domain.MouseActivity, next.Add(9*time.Hour), next.Add(9*time.Hour+10*time.Minute))
+	saveActivity(t, repo, domain.MouseActivity, next.Add(9*time.Hour+10*time.Minute+30*time.Second), next.Add(9*time.Hour+20*time.Minute))
+
+	plan := domain.RetentionPlan{PurgeBefore: next, CompactBefore: next.AddDate(0, 0, 1), MergeGap: time.Minute}
+	if _, err := repo.ApplyRetention(ctx, plan, true); err != nil {
+		t.Fatal(err)
+	}
+	if entries := retentionAudit(t, db); len(entries) != 0 {
+		t.Errorf("試執行寫入了 %d 筆稽核記錄", len(entries))
+	}
+
+	for range 2 {
+		if _, err := repo.ApplyRetention(ctx, plan, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := retentionAudit(t, db)
+	if len(entries) != 1 {
+		t.Fatalf("保存政策寫入 %d 筆稽核記錄，應為 1 筆: %+v", len(entries), entries)
+	}
+
+	entry := entries[0]
+	if entry.Operation != domain.AuditBulk || entry.ActivityID != 0 || entry.Before != nil || entry.After != nil {
+		t.Errorf("稽核記錄 = %+v，應為不含快照的批次記錄", entry)
+	}
+	for _, want := range []string{
+		"刪除 2026-01-11 00:00:00 之前結束的活動 3 筆，ID 1-3，時間 2026-01-10 09:00:00 至 2026-01-10 14:00:00",
+		"刪除 1 筆，ID 5，併入 1 筆，ID 4，時間 2026-01-11 09:00:00 至 2026-01-11 09:20:00",
+	} {
+		if !strings.Contains(entry.Detail, want) {
+			t.Errorf("稽核記錄的說明 %q 不包含 %q", entry.Detail, want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"main/internal/domain"
+)
+
+// hourSeconds 是每小時彙總的桶寬，桶以 Unix 時間對齊整點
+const hourSeconds = 60 * 60
+
+// rebuildHourlyRollupSQL 將每筆已結束的活動展開成整點的桶後重新計算每小時彙總
+const rebuildHourlyRollupSQL = `
+	WITH RECURSIVE buckets (activity_type, start_time, end_time, bucket_start) AS (
+		SELECT activity_type, start_time, end_time, start_time - start_time % 3600
+		FROM activities
+		WHERE end_time IS NOT NULL AND end_time > start_time
+		UNION ALL
+		SELECT activity_type, start_time, end_time, bucket_start + 3600
+		FROM buckets
+		WHERE bucket_start + 3600 < end_time
+	)
+	INSERT INTO activity_rollup_hourly (bucket_start, activity_type, seconds)
+	SELECT bucket_start, activity_type,
+		SUM(MIN(end_time, bucket_start + 3600) - MAX(start_time, bucket_start))
+	FROM buckets
+	GROUP BY bucket_start, activity_type
+`
+
+// overlapRollupType 是彙總表中記錄不同類型活動重疊時間的類型，
+// 任一輸入的總時間為各類型時間相加後扣除重疊的部分
+const overlapRollupType domain.ActivityType = "overlap"
+
+// overlapBoundsSQL 定義 overlapPairsSQL 使用的 bounds：查詢的範圍 [?, ?) 與最長的活動時間。
+// 與範圍重疊的活動開始時間不會早於範圍開始減去最長的活動時間，
+// 兩邊的活動都能以 start_time 索引限定範圍，不必掃描整個歷史。
+const overlapBoundsSQL = `
+	bounds (range_start, range_end, max_duration) AS (
+		SELECT ?, ?, (
+			SELECT COALESCE(MAX(end_time - start_time), 0)
+			FROM activities
+			WHERE end_time IS NOT NULL
+		)
+	)
+`
+
+// overlapPairsSQL 列出與 bounds 範圍重疊、不同類型、已結束的活動兩兩重疊的時段，需搭配 overlapBoundsSQL。
+// 同類型的活動不會重疊，手動輸入的活動不與任何活動重疊，只有滑鼠與鍵盤會互相重疊，
+// 因此扣除兩兩重疊即為聯集。
+const overlapPairsSQL = `
+	SELECT MAX(a.start_time, b.start_time) AS start_time, MIN(a.end_time, b.end_time) AS end_time
+` + overlapJoinSQL
+
+// overlapJoinSQL 是 overlapPairsSQL 的 FROM 與 WHERE 子句，a 與 b 是重疊的兩筆活動
+const overlapJoinSQL = `
+	FROM bounds r
+	JOIN activities a
+		ON a.start_time >= r.range_start - r.max_duration AND a.start_time < r.range_end
+	JOIN activities b
+		ON b.start_time >= a.start_time - r.max_duration AND b.start_time < a.end_time
+		AND a.activity_type < b.activity_type AND b.end_time > a.start_time
+	WHERE a.end_time IS NOT NULL AND a.end_time > a.start_time AND a.end_time > r.range_start
+		AND b.end_time IS NOT NULL AND b.end_time > b.start_time
+`
+
+// allTimeBounds 是 overlapBoundsSQL 涵蓋所有活動的範圍
+var allTimeBounds = []interface{}{int64(0), int64(math.MaxInt64)}
+
+// rebuildHourlyOverlapSQL 重新計算每小時彙總中的重疊時間，參數為 allTimeBounds
+const rebuildHourlyOverlapSQL = `
+	WITH RECURSIVE ` + overlapBoundsSQL + `,
+	overlaps (start_time, end_time) AS (` + overlapPairsSQL + `),
+	buckets (start_time, end_time, bucket_start) AS (
+		SELECT start_time, end_time, start_time - start_time % 3600
+		FROM overlaps
+		UNION ALL
+		SELECT start_time, end_time, bucket_start + 3600
+		FROM buckets
+		WHERE bucket_start + 3600 < end_time
+	)
+	INSERT INTO activity_rollup_hourly (bucket_start, activity_type, seconds)
+	SELECT bucket_start, 'overlap',
+		SUM(MIN(end_time, bucket_start + 3600) - MAX(start_time, bucket_start))
+	FROM buckets
+	GROUP BY bucket_start
+`
+
+type rollupKey struct {
+	start        int64
+	activityType domain.ActivityType
+}
+
+// rollupDelta 累積一次交易中對彙總表的增減，最後一次寫入
+type rollupDelta struct {
+	cal    *domain.Calendar // nil 表示每日彙總已失效，只更新每小時彙總
+	hourly map[rollupKey]int64
+	daily  map[rollupKey]int64
+}
+
+func newRollupDelta(cal *domain.Calendar) *rollupDelta {
+	return &rollupDelta{
+		cal:    cal,
+		hourly: make(map[rollupKey]int64),
+		daily:  make(map[rollupKey]int64),
+	}
+}
+
+// add 將一筆已結束的活動加入（sign=1）或移出（sign=-1）彙總，
+// 並依 tx 中其他類型的活動調整重疊時間，因此必須依資料變更的順序呼叫
+func (d *rollupDelta) add(ctx context.Context, tx *sql.Tx, activity domain.Activity, sign int64) error {
+	if !activity.IsEnded() || activity.EndTimeUnix <= activity.StartTimeUnix {
+		return nil
+	}
+	d.addInterval(activity.Type, activity.StartTimeUnix, activity.EndTimeUnix, sign)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT start_time, end_time
+		FROM activities
+		WHERE activity_type != ? AND end_time IS NOT NULL AND end_time > start_time
+			AND start_time < ? AND end_time > ?
+	`, activity.Type, activity.EndTimeUnix, activity.StartTimeUnix)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start, end int64
+		if err := rows.Scan(&start, &end); err != nil {
+			return err
+		}
+		d.addInterval(overlapRollupType, max(start, activity.StartTimeUnix), min(end, activity.EndTimeUnix), sign)
+	}
+	return rows.Err()
+}
+
+// addInterval 將 [start, end) 的時間依每小時與每日邊界加入 activityType 的彙總
+func (d *rollupDelta) addInterval(activityType domain.ActivityType, start, end int64, sign int64) {
+	if end <= start {
+		return
+	}
+
+	for bucket := start - start%hourSeconds; bucket < end; bucket += hourSeconds {
+		seconds := min(end, bucket+hourSeconds) - max(start, bucket)
+		d.hourly[rollupKey{bucket, activityType}] += sign * seconds
+	}
+
+	if d.cal == nil {
+		return
+	}
+	for _, slice := range d.cal.Split(time.Unix(start, 0), time.Unix(end, 0)) {
+		seconds := slice.End.Unix() - slice.Start.Unix()
+		d.daily[rollupKey{slice.DayStart.Unix(), activityType}] += sign * seconds
+	}
+}
+
+func (d *rollupDelta) apply(ctx context.Context, tx *sql.Tx) error {
+	if err := applyRollup(ctx, tx, "activity_rollup_hourly", "bucket_start", d.hourly); err != nil {
+		return err
+	}
+
+	if d.cal == nil {
+		// 每日彙總沒有跟著更新，清除狀態讓下次 UseCalendar 重新建立
+		if len(d.hourly) == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM rollup_state`)
+		return err
+	}
+	return applyRollup(ctx, tx, "activity_rollup_daily", "day_start", d.daily)
+}
+
+func applyRollup(ctx context.Context, tx *sql.Tx, table, column string, delta map[rollupKey]int64) error {
+	for key, seconds := range delta {
+		if seconds == 0 {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %[1]s (%[2]s, activity_type, seconds)
+			VALUES (?, ?, ?)
+			ON CONFLICT(%[2]s, activity_type) DO UPDATE SET seconds = seconds + excluded.seconds
+		`, table, column), key.start, key.activityType, seconds); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE seconds <= 0`, table))
+	return err
+}
+
+// UseCalendar 設定每日彙總的每日邊界，與資料庫中的彙總不一致時重新建立每日彙總
+func (r *SQLiteActivityRepository) UseCalendar(ctx context.Context, cal domain.Calendar) error {
+	r.rollupMu.Lock()
+	defer r.rollupMu.Unlock()
+
+	var current string
+	err := r.db.QueryRowContext(ctx, `SELECT calendar FROM rollup_state WHERE id = 1`).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("讀取彙總狀態失敗: %v", err)
+		return err
+	}
+
+	if current != cal.String() {
+		log.Printf("每日邊界改變，重新建立每日彙總: %q -> %q", current, cal.String())
+		if err := r.rebuild(ctx, cal, false); err != nil {
+			return err
+		}
+	}
+
+	r.rollupCal = &cal
+	return nil
+}
+
+// RebuildRollups 從原始活動記錄重新產生每小時與每日彙總
+func (r *SQLiteActivityRepository) RebuildRollups(ctx context.Context) error {
+	r.rollupMu.Lock()
+	defer r.rollupMu.Unlock()
+
+	if r.rollupCal == nil {
+		return fmt.Errorf("尚未設定每日彙總的 Calendar")
+	}
+	return r.rebuild(ctx, *r.rollupCal, true)
+}
+
+// beginRollupTx 開始一個會更新彙總的交易，回傳的 done 必須在交易結束後呼叫
+func (r *SQLiteActivityRepository) beginRollupTx(ctx context.Context) (*sql.Tx, *rollupDelta, func(), error) {
+	r.rollupMu.Lock()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.rollupMu.Unlock()
+		return nil, nil, nil, err
+	}
+
+	done := func() {
+		tx.Rollback()
+		r.rollupMu.Unlock()
+	}
+	return tx, newRollupDelta(r.rollupCal), done, nil
+}
+
+func (r *SQLiteActivityRepository) rebuild(ctx context.Context, cal domain.Calendar, hourly bool) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if hourly {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM activity_rollup_hourly`); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, rebuildHourlyRollupSQL); err != nil {
+			log.Printf("重新建立每小時彙總失敗: %v", err)
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, rebuildHourlyOverlapSQL, allTimeBounds...); err != nil {
+			log.Printf("重新建立每小時重疊時間失敗: %v", err)
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activity_rollup_daily`); err != nil {
+		return err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, start_time, end_time, activity_type, project_id, manual
+		FROM activities
+		WHERE end_time IS NOT NULL AND end_time > start_time
+	`)
+	if err != nil {
+		return err
+	}
+
+	delta := newRollupDelta(&cal)
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		delta.addInterval(activity.Type, activity.StartTimeUnix, activity.EndTimeUnix, 1)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	overlaps, err := tx.QueryContext(ctx, `WITH `+overlapBoundsSQL+overlapPairsSQL, allTimeBounds...)
+	if err != nil {
+		return err
+	}
+	for overlaps.Next() {
+		var start, end int64
+		if err := overlaps.Scan(&start, &end); err != nil {
+			overlaps.Close()
+			return err
+		}
+		delta.addInterval(overlapRollupType, start, end, 1)
+	}
+	overlaps.Close()
+	if err := overlaps.Err(); err != nil {
+		return err
+	}
+
+	if err := applyRollup(ctx, tx, "activity_rollup_daily", "day_start", delta.daily); err != nil {
+		log.Printf("重新建立每日彙總失敗: %v", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rollup_state (id, calendar)
+		VALUES (1, ?)
+		ON CONFLICT(id) DO UPDATE SET calendar = excluded.calendar
+	`, cal.String()); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("彙總重新建立完成: 每日邊界=%s", cal.String())
+	return nil
+}
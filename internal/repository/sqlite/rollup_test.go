@@ -0,0 +1,91 @@
+package sqlite_test
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+)
+
+// TestIncrementalRollupsMatchRebuildAcrossHourAndDayBoundaries 以跨過整點與午夜、滑鼠與鍵盤重疊的活動
+// 逐筆更新彙總，確認結果與從原始記錄完整重建的彙總相同
+func TestIncrementalRollupsMatchRebuildAcrossHourAndDayBoundaries(t *testing.T) {
+	day := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	db, repo := newTestRepo(t, next.Add(12*time.Hour))
+	ctx := context.Background()
+
+	// 滑鼠活動開始時還沒有結束時間，之後才以 UpdateEndTime 結束在隔天 01:20
+	mouse := domain.Activity{Type: domain.MouseActivity}
+	mouse.SetStartTime(day.Add(22*time.Hour + 40*time.Minute))
+	mouse, err := repo.Save(ctx, mouse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveActivity(t, repo, domain.KeyboardActivity, day.Add(23*time.Hour+50*time.Minute), next.Add(10*time.Minute))
+	keyboard := saveActivity(t, repo, domain.KeyboardActivity, next.Add(50*time.Minute), next.Add(time.Hour+5*time.Minute))
+	mouse.SetEndTime(next.Add(time.Hour + 20*time.Minute))
+	if err := repo.UpdateEndTime(ctx, mouse); err != nil {
+		t.Fatal(err)
+	}
+
+	key := func(table string, start time.Time, activityType string) string {
+		return "activity_rollup_" + table + ":" + start.Format(time.DateTime) + ":" + activityType
+	}
+	want := map[string]int64{
+		key("hourly", day.Add(22*time.Hour), "mouse"):    1200,
+		key("hourly", day.Add(23*time.Hour), "mouse"):    3600,
+		key("hourly", next, "mouse"):                     3600,
+		key("hourly", next.Add(time.Hour), "mouse"):      1200,
+		key("hourly", day.Add(23*time.Hour), "keyboard"): 600,
+		key("hourly", next, "keyboard"):                  1200,
+		key("hourly", next.Add(time.Hour), "keyboard"):   300,
+		key("hourly", day.Add(23*time.Hour), "overlap"):  600,
+		key("hourly", next, "overlap"):                   1200,
+		key("hourly", next.Add(time.Hour), "overlap"):    300,
+		key("daily", day, "mouse"):                       4800,
+		key("daily", next, "mouse"):                      4800,
+		key("daily", day, "keyboard"):                    600,
+		key("daily", next, "keyboard"):                   1500,
+		key("daily", day, "overlap"):                     600,
+		key("daily", next, "overlap"):                    1500,
+	}
+	if got := checkRollupsMatchRebuild(t, db, repo); !maps.Equal(got, want) {
+		t.Errorf("彙總 = %v\n應為 %v", got, want)
+	}
+
+	// 修改後鍵盤活動超過滑鼠的結束時間，重疊只算到 01:20
+	keyboard.SetStartTime(next.Add(55 * time.Minute))
+	keyboard.SetEndTime(next.Add(time.Hour + 30*time.Minute))
+	if err := repo.UpdateActivity(ctx, keyboard, domain.AuditManual); err != nil {
+		t.Fatal(err)
+	}
+	got := checkRollupsMatchRebuild(t, db, repo)
+	if seconds := got[key("hourly", next.Add(time.Hour), "overlap")]; seconds != 1200 {
+		t.Errorf("修改後 01:00 的重疊時間 = %d 秒，應為 1200 秒", seconds)
+	}
+	if seconds := got[key("daily", next, "overlap")]; seconds != 2100 {
+		t.Errorf("修改後隔天的重疊時間 = %d 秒，應為 2100 秒", seconds)
+	}
+
+	// 刪除跨過午夜的鍵盤活動後，前一天不再有鍵盤與重疊時間
+	activities, err := repo.GetActivities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, activity := range activities {
+		if activity.Type == domain.KeyboardActivity && activity.ID != keyboard.ID {
+			if err := repo.DeleteActivity(ctx, activity.ID, domain.AuditManual); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	got = checkRollupsMatchRebuild(t, db, repo)
+	for _, activityType := range []string{"keyboard", "overlap"} {
+		if seconds, ok := got[key("daily", day, activityType)]; ok && seconds != 0 {
+			t.Errorf("刪除後前一天的 %s = %d 秒，應為 0", activityType, seconds)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+var _ repository.StatsRepository = &SQLiteStatsRepository{}
+
+// statsDaysPerQuery 是每次查詢帶入的日期數量，避免超過 SQLite 的參數上限
+const statsDaysPerQuery = 400
+
+type SQLiteStatsRepository struct {
+	db *sql.DB
+}
+
+func NewSQLiteStatsRepository(db *sql.DB) *SQLiteStatsRepository {
+	return &SQLiteStatsRepository{db: db}
+}
+
+// GetDailyStats 回傳已結束活動的每日時間。
+// 每日彙總的邊界與 cal 相同時直接讀取彙總表，否則從原始活動記錄彙總。
+func (r *SQLiteStatsRepository) GetDailyStats(ctx context.Context, cal domain.Calendar) ([]domain.DailyStats, error) {
+	return r.dailyStats(ctx, cal, math.MinInt64)
+}
+
+// GetDailyStatsSince 同 GetDailyStats，但只彙總 since 所在的那一天與之後的日期
+func (r *SQLiteStatsRepository) GetDailyStatsSince(ctx context.Context, cal domain.Calendar, since time.Time) ([]domain.DailyStats, error) {
+	return r.dailyStats(ctx, cal, cal.DayStart(since).Unix())
+}
+
+// dailyStats 彙總 day_start 不早於 from 的每日時間，from 必須是每日邊界
+func (r *SQLiteStatsRepository) dailyStats(ctx context.Context, cal domain.Calendar, from int64) ([]domain.DailyStats, error) {
+	var rollupCalendar string
+	err := r.db.QueryRowContext(ctx, `SELECT calendar FROM rollup_state WHERE id = 1`).Scan(&rollupCalendar)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("讀取彙總狀態失敗: %v", err)
+		return nil, err
+	}
+
+	var stats []domain.DailyStats
+	if rollupCalendar == cal.String() {
+		stats, err = r.dailyStatsFromRollup(ctx, cal, from)
+	} else {
+		stats, err = r.dailyStatsFromActivities(ctx, cal, from)
+	}
+	if err != nil {
+		log.Printf("彙總每日統計失敗: %v", err)
+		return nil, err
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Date.After(stats[j].Date) // 降序排序，最新的日期在前
+	})
+
+	if err := r.fillAppStats(ctx, cal, stats); err != nil {
+		log.Printf("彙總應用程式統計失敗: %v", err)
+		return nil, err
+	}
+	if err := r.fillProjectStats(ctx, cal, stats); err != nil {
+		log.Printf("彙總專案統計失敗: %v", err)
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// fillAppStats 將已結束活動的時間依焦點時段分配到各應用程式，只查詢有焦點記錄之後的日期
+func (r *SQLiteStatsRepository) fillAppStats(ctx context.Context, cal domain.Calendar, stats []domain.DailyStats) error {
+	var first sql.NullInt64
+	if err := r.db.QueryRowContext(ctx, `SELECT MIN(start_time) FROM focus_periods`).Scan(&first); err != nil {
+		return err
+	}
+	if !first.Valid {
+		return nil
+	}
+
+	firstDay := cal.DayStart(time.Unix(first.Int64, 0))
+	var days []time.Time
+	index := make(map[int64]int)
+	for i, stat := range stats {
+		if !stat.Date.Before(firstDay) {
+			days = append(days, stat.Date)
+			index[stat.Date.Unix()] = i
+		}
+	}
+
+	for i := 0; i < len(days); i += statsDaysPerQuery {
+		j := min(i+statsDaysPerQuery, len(days))
+		err := r.aggregateApps(ctx, cal, days[i:j], func(dayStart int64, app string, d time.Duration) {
+			if idx, ok := index[dayStart]; ok {
+				stats[idx].Apps = append(stats[idx].Apps, domain.AppStats{App: app, Duration: d})
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stats {
+		sort.Slice(stats[i].Apps, func(a, b int) bool {
+			return stats[i].Apps[a].Duration > stats[i].Apps[b].Duration
+		})
+	}
+	return nil
+}
+
+// dayBoundsSQL 以 days 的每一天作為 overlapJoinSQL 的一個 bounds 範圍，需接在 days 之後。
+// 每一天的活動與重疊時段都以 start_time 索引找出，不必將整段期間的記錄逐一與每一天比對。
+// MATERIALIZED 讓最長的活動時間在每次查詢只計算一次。
+const dayBoundsSQL = `
+	bounds (range_start, range_end, max_duration) AS MATERIALIZED (
+		SELECT day_start, day_end, (
+			SELECT COALESCE(MAX(end_time - start_time), 0)
+			FROM activities
+			WHERE end_time IS NOT NULL
+		)
+		FROM days
+	)
+`
+
+// dayOverlapPairsSQL 同 overlapPairsSQL，但搭配 dayBoundsSQL 依日期列出，同一段重疊可能出現在它跨過的每一天
+const dayOverlapPairsSQL = `
+	SELECT r.range_start AS day_start, r.range_end AS day_end,
+		MAX(a.start_time, b.start_time) AS start_time, MIN(a.end_time, b.end_time) AS end_time
+` + overlapJoinSQL
+
+// trackedOverlapPairsSQL 同 dayOverlapPairsSQL，但只包含追蹤器記錄、沒有手動新增或修改過的活動。
+// manual 前的 + 讓 SQLite 不為這個等式建立自動索引，仍以 start_time 索引限定範圍。
+const trackedOverlapPairsSQL = dayOverlapPairsSQL + `
+		AND +a.manual = 0 AND +b.manual = 0
+`
+
+// focusBoundsSQL 定義 focus_bounds：最長的焦點時段，仍取得焦點的時段算到最後一天結束。
+// 與活動重疊的焦點時段開始時間不會早於活動開始減去最長的焦點時段，可以用 start_time 索引限定範圍。
+// 已結束與仍取得焦點的時段分開計算，兩者都能直接由索引取得。
+const focusBoundsSQL = `
+	focus_bounds (max_focus) AS (
+		SELECT MAX(
+			(SELECT COALESCE(MAX(end_time - start_time), 0) FROM focus_periods),
+			COALESCE((SELECT MAX(range_end) FROM bounds) - (
+				SELECT MIN(start_time) FROM focus_periods WHERE end_time IS NULL
+			), 0)
+		)
+	)
+`
+
+// aggregateApps 依焦點時段的應用程式計算取得焦點時的活動時間：
+// 各類型活動與焦點時段的交集相加後，扣除不同類型重疊部分與焦點時段的交集。
+// 手動新增或修改過的活動不是在焦點視窗上的輸入，不計入任何應用程式。
+// 活動與焦點時段都以 start_time 索引限定範圍，查詢時間只與 days 內的記錄數量有關。
+func (r *SQLiteStatsRepository) aggregateApps(ctx context.Context, cal domain.Calendar, days []time.Time, add func(dayStart int64, app string, d time.Duration)) error {
+	values, args := dayValues(cal, days)
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH days (day_start, day_end) AS (VALUES `+values+`),
+		`+dayBoundsSQL+`,
+		`+focusBoundsSQL+`,
+		overlaps (day_start, day_end, start_time, end_time) AS (`+trackedOverlapPairsSQL+`),
+		spans (day_start, app, start_time, end_time, sign) AS (
+			SELECT d.range_start, f.app,
+				MAX(a.start_time, f.start_time, d.range_start),
+				MIN(a.end_time, COALESCE(f.end_time, d.range_end), d.range_end),
+				1
+			FROM focus_bounds fb, bounds d
+			JOIN activities a
+				ON a.start_time >= d.range_start - d.max_duration AND a.start_time < d.range_end
+				AND a.end_time > d.range_start
+			JOIN focus_periods f
+				ON f.start_time >= a.start_time - fb.max_focus AND f.start_time < a.end_time
+				AND (f.end_time IS NULL OR f.end_time > a.start_time)
+			WHERE a.end_time IS NOT NULL AND +a.manual = 0
+			UNION ALL
+			SELECT o.day_start, f.app,
+				MAX(o.start_time, f.start_time, o.day_start),
+				MIN(o.end_time, COALESCE(f.end_time, o.day_end), o.day_end),
+				-1
+			FROM focus_bounds fb, overlaps o
+			JOIN focus_periods f
+				ON f.start_time >= o.start_time - fb.max_focus AND f.start_time < o.end_time
+				AND (f.end_time IS NULL OR f.end_time > o.start_time)
+		)
+		SELECT day_start, app, SUM(sign * (end_time - start_time))
+		FROM spans
+		WHERE end_time > start_time
+		GROUP BY day_start, app
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dayStart, seconds int64
+		var app string
+		if err := rows.Scan(&dayStart, &app, &seconds); err != nil {
+			return err
+		}
+		if seconds > 0 {
+			add(dayStart, app, time.Duration(seconds)*time.Second)
+		}
+	}
+	return rows.Err()
+}
+
+// fillProjectStats 依活動的 project_id 計算各專案的每日時間，手動輸入的活動也計入所屬的專案
+func (r *SQLiteStatsRepository) fillProjectStats(ctx context.Context, cal domain.Calendar, stats []domain.DailyStats) error {
+	days := make([]time.Time, len(stats))
+	index := make(map[int64]int)
+	for i, stat := range stats {
+		days[i] = stat.Date
+		index[stat.Date.Unix()] = i
+	}
+
+	for i := 0; i < len(days); i += statsDaysPerQuery {
+		j := min(i+statsDaysPerQuery, len(days))
+		err := r.aggregateProjects(ctx, cal, days[i:j], func(dayStart, projectID int64, d time.Duration) {
+			if idx, ok := index[dayStart]; ok {
+				stats[idx].Projects = append(stats[idx].Projects, domain.ProjectStats{ProjectID: projectID, Duration: d})
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := range stats {
+		sort.Slice(stats[i].Projects, func(a, b int) bool {
+			return stats[i].Projects[a].Duration > stats[i].Projects[b].Duration
+		})
+	}
+	return nil
+}
+
+// projectOverlapPairsSQL 同 dayOverlapPairsSQL，但只包含屬於同一個專案的兩筆活動，並列出它們的專案。
+// project_id 前的 + 讓 SQLite 不以專案索引找 b，大多數活動沒有專案時會掃描整個表。
+const projectOverlapPairsSQL = `
+	SELECT r.range_start AS day_start, r.range_end AS day_end, a.project_id,
+		MAX(a.start_time, b.start_time) AS start_time, MIN(a.end_time, b.end_time) AS end_time
+` + overlapJoinSQL + `
+		AND +a.project_id IS +b.project_id
+`
+
+// aggregateProjects 計算各專案的活動時間：屬於專案的活動時間相加後，扣除同一個專案內不同類型重疊的時間，
+// 讓每個專案的時間是它的活動的聯集。沒有專案的活動以 projectID 0 回報。
+func (r *SQLiteStatsRepository) aggregateProjects(ctx context.Context, cal domain.Calendar, days []time.Time, add func(dayStart, projectID int64, d time.Duration)) error {
+	values, args := dayValues(cal, days)
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH days (day_start, day_end) AS (VALUES `+values+`),
+		`+dayBoundsSQL+`,
+		overlaps (day_start, day_end, project_id, start_time, end_time) AS (`+projectOverlapPairsSQL+`),
+		spans (day_start, project_id, start_time, end_time, sign) AS (
+			SELECT d.range_start, a.project_id,
+				MAX(a.start_time, d.range_start),
+				MIN(a.end_time, d.range_end),
+				1
+			FROM bounds d
+			JOIN activities a
+				ON a.start_time >= d.range_start - d.max_duration AND a.start_time < d.range_end
+				AND a.end_time > d.range_start
+			WHERE a.end_time IS NOT NULL
+			UNION ALL
+			SELECT day_start, project_id,
+				MAX(start_time, day_start),
+				MIN(end_time, day_end),
+				-1
+			FROM overlaps
+		)
+		SELECT day_start, COALESCE(project_id, 0), SUM(sign * (end_time - start_time))
+		FROM spans
+		WHERE end_time > start_time
+		GROUP BY day_start, project_id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dayStart, projectID, seconds int64
+		if err := rows.Scan(&dayStart, &projectID, &seconds); err != nil {
+			return err
+		}
+		if seconds > 0 {
+			add(dayStart, projectID, time.Duration(seconds)*time.Second)
+		}
+	}
+	return rows.Err()
+}
+
+// GetHourlyStats 讀取每小時彙總，彙總的桶以 Unix 時間對齊整點
+func (r *SQLiteStatsRepository) GetHourlyStats(ctx context.Context, from, to time.Time) ([]domain.HourlyStats, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT bucket_start, activity_type, seconds
+		FROM activity_rollup_hourly
+		WHERE bucket_start >= ? AND bucket_start < ?
+		ORDER BY bucket_start ASC
+	`, from.Unix()-from.Unix()%hourSeconds, to.Unix())
+	if err != nil {
+		log.Printf("查詢每小時彙總失敗: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.HourlyStats
+	for rows.Next() {
+		var bucketStart, seconds int64
+		var activityType domain.ActivityType
+		if err := rows.Scan(&bucketStart, &activityType, &seconds); err != nil {
+			return nil, err
+		}
+
+		if len(result) == 0 || result[len(result)-1].Hour.Unix() != bucketStart {
+			result = append(result, domain.HourlyStats{Hour: time.Unix(bucketStart, 0).In(from.Location())})
+		}
+
+		stats := &result[len(result)-1]
+		accumulate(activityType, time.Duration(seconds)*time.Second,
+			&stats.TotalDuration, &stats.MouseDuration, &stats.KeyboardDuration, &stats.ManualDuration)
+	}
+	return result, rows.Err()
+}
+
+func (r *SQLiteStatsRepository) dailyStatsFromRollup(ctx context.Context, cal domain.Calendar, from int64) ([]domain.DailyStats, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT day_start, activity_type, seconds
+		FROM activity_rollup_daily
+		WHERE day_start >= ?
+	`, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectDailyStats(rows, cal)
+}
+
+// dailyStatsFromActivities 在 SQL 中彙總原始活動記錄，
+// 每日邊界由 cal 在 Go 端計算後帶入查詢，跨日的活動會依邊界切分到各天。
+func (r *SQLiteStatsRepository) dailyStatsFromActivities(ctx context.Context, cal domain.Calendar, from int64) ([]domain.DailyStats, error) {
+	var first, last sql.NullInt64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT MIN(start_time), MAX(end_time)
+		FROM activities
+		WHERE end_time IS NOT NULL AND end_time > start_time AND end_time > ?
+	`, from).Scan(&first, &last)
+	if err != nil {
+		return nil, err
+	}
+	if !first.Valid || !last.Valid {
+		return nil, nil
+	}
+
+	var days []time.Time
+	end := time.Unix(last.Int64, 0)
+	for day := cal.DayStart(time.Unix(max(first.Int64, from), 0)); day.Before(end); day = cal.NextDayStart(day) {
+		days = append(days, day)
+	}
+
+	var result []domain.DailyStats
+	for i := 0; i < len(days); i += statsDaysPerQuery {
+		j := min(i+statsDaysPerQuery, len(days))
+		stats, err := r.aggregate(ctx, cal, days[i:j])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, stats...)
+	}
+	return result, nil
+}
+
+func (r *SQLiteStatsRepository) aggregate(ctx context.Context, cal domain.Calendar, days []time.Time) ([]domain.DailyStats, error) {
+	values, args := dayValues(cal, days)
+
+	rows, err := r.db.QueryContext(ctx, `
+		WITH days (day_start, day_end) AS (VALUES `+values+`),
+		`+dayBoundsSQL+`,
+		overlaps (day_start, day_end, start_time, end_time) AS (`+dayOverlapPairsSQL+`)
+		SELECT d.range_start, a.activity_type,
+			SUM(MIN(a.end_time, d.range_end) - MAX(a.start_time, d.range_start))
+		FROM bounds d
+		JOIN activities a
+			ON a.start_time >= d.range_start - d.max_duration AND a.start_time < d.range_end
+			AND a.end_time > d.range_start
+		WHERE a.end_time IS NOT NULL
+		GROUP BY d.range_start, a.activity_type
+		UNION ALL
+		SELECT day_start, ?,
+			SUM(MIN(end_time, day_end) - MAX(start_time, day_start))
+		FROM overlaps
+		WHERE start_time < day_end AND end_time > day_start
+		GROUP BY day_start
+	`, append(args, overlapRollupType)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return collectDailyStats(rows, cal)
+}
+
+// dayValues 回傳 days 的 VALUES 子句與參數，每一天是 [day_start, day_end) 的範圍
+func dayValues(cal domain.Calendar, days []time.Time) (string, []interface{}) {
+	values := make([]string, len(days))
+	args := make([]interface{}, 0, len(days)*2)
+	for i, day := range days {
+		values[i] = "(?, ?)"
+		args = append(args, day.Unix(), cal.NextDayStart(day).Unix())
+	}
+	return strings.Join(values, ", "), args
+}
+
+// collectDailyStats 將 (day_start, activity_type, seconds) 的查詢結果組成每日統計
+func collectDailyStats(rows *sql.Rows, cal domain.Calendar) ([]domain.DailyStats, error) {
+	statsMap := make(map[int64]*domain.DailyStats)
+	var order []int64
+	for rows.Next() {
+		var dayStart, seconds int64
+		var activityType domain.ActivityType
+		if err := rows.Scan(&dayStart, &activityType, &seconds); err != nil {
+			return nil, err
+		}
+
+		stats, exists := statsMap[dayStart]
+		if !exists {
+			stats = &domain.DailyStats{Date: cal.DayStart(time.Unix(dayStart, 0))}
+			statsMap[dayStart] = stats
+			order = append(order, dayStart)
+		}
+
+		accumulate(activityType, time.Duration(seconds)*time.Second,
+			&stats.TotalDuration, &stats.MouseDuration, &stats.KeyboardDuration, &stats.ManualDuration)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.DailyStats, 0, len(order))
+	for _, dayStart := range order {
+		result = append(result, *statsMap[dayStart])
+	}
+	return result, nil
+}
+
+// accumulate 將一筆彙總時間加入統計，重疊時間從總時間扣除，讓總時間是各類型的聯集
+func accumulate(activityType domain.ActivityType, duration time.Duration, total, mouse, keyboard, manual *time.Duration) {
+	switch activityType {
+	case overlapRollupType:
+		*total -= duration
+	case domain.MouseActivity:
+		*total += duration
+		*mouse += duration
+	case domain.ManualActivity:
+		*total += duration
+		*manual += duration
+	default:
+		*total += duration
+		*keyboard += duration
+	}
+}
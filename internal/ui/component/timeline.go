@@ -1,7 +1,9 @@
 package component
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
 	"image/color"
 	"log"
 	"time"
@@ -12,20 +14,26 @@ import (
 
 	"main/internal/domain"
 	"main/internal/usecase"
+	"main/pkg/clock"
 )
 
 type TimelineChart struct {
 	widget.BaseWidget
 	tracker   *usecase.ActivityTracker
+	intensity *usecase.IntensityRecorder
+	clock     clock.Clock
 	startTime time.Time
 	endTime   time.Time
 }
 
-func NewTimelineChart(tracker *usecase.ActivityTracker) *TimelineChart {
+func NewTimelineChart(tracker *usecase.ActivityTracker, intensity *usecase.IntensityRecorder, clk clock.Clock) *TimelineChart {
+	startTime, endTime := tracker.Today()
 	chart := &TimelineChart{
 		tracker:   tracker,
-		startTime: time.Now().Truncate(24 * time.Hour),
-		endTime:   time.Now().Truncate(24 * time.Hour).Add(24 * time.Hour),
+		intensity: intensity,
+		clock:     clk,
+		startTime: startTime,
+		endTime:   endTime,
 	}
 	chart.ExtendBaseWidget(chart)
 	return chart
@@ -68,10 +76,8 @@ func (r *timelineRenderer) Objects() []fyne.CanvasObject {
 func (r *timelineRenderer) Destroy() {}
 
 func (r *timelineRenderer) drawTimeline(size fyne.Size) {
-	// 使用本地時間
-	today := time.Now().Local().Truncate(24 * time.Hour)
-	r.chart.startTime = today
-	r.chart.endTime = today.Add(24 * time.Hour)
+	// 依照設定的時區與每日開始時間決定今天的範圍
+	r.chart.startTime, r.chart.endTime = r.chart.tracker.Today()
 
 	// 清除舊的矩形
 	r.rects = nil
@@ -87,109 +93,257 @@ func (r *timelineRenderer) drawTimeline(size fyne.Size) {
 	background.Move(innerPos)
 	r.rects = append(r.rects, background)
 
-	// 繪製背景時間刻度
-	for i := 0; i < 24; i++ {
+	// 繪製背景時間刻度，夏令時間切換的日子可能是 23 或 25 小時
+	for hour := r.chart.startTime; hour.Before(r.chart.endTime); hour = hour.Add(time.Hour) {
 		// 垂直線
 		line := canvas.NewLine(color.NRGBA{R: 200, G: 200, B: 200, A: 255})
-		x := innerPos.X + float32(i)*innerSize.Width/24
+		x := innerPos.X + r.timeToX(hour, innerSize.Width)
 		line.Position1 = fyne.NewPos(x, innerPos.Y)
 		line.Position2 = fyne.NewPos(x, innerPos.Y+innerSize.Height-20)
 		line.StrokeWidth = 1
 		r.rects = append(r.rects, line)
 
 		// 時間標籤
-		label := canvas.NewText(fmt.Sprintf("%02d:00", i), color.NRGBA{R: 100, G: 100, B: 100, A: 255})
+		label := canvas.NewText(fmt.Sprintf("%02d:00", hour.Hour()), color.NRGBA{R: 100, G: 100, B: 100, A: 255})
 		label.TextSize = 10
 		label.Move(fyne.NewPos(x-10, innerPos.Y+innerSize.Height-15))
 		r.rects = append(r.rects, label)
 	}
 
-	activities, err := r.chart.tracker.GetTodayActivities()
+	sessions, err := r.chart.tracker.GetTodaySessions()
 	if err != nil {
-		log.Printf("獲取今日活動失敗: %v", err)
+		log.Printf("獲取今日工作階段失敗: %v", err)
 		return
 	}
 
 	// 記錄並打印活動時間，用於調試
-	log.Printf("今日活動數量: %d", len(activities))
-	for _, activity := range activities {
-		endTimeStr := "進行中"
-		if activity.IsEnded() {
-			endTimeStr = activity.EndTime().Format("15:04:05")
+	log.Printf("今日工作階段數量: %d", len(sessions))
+	for _, session := range sessions {
+		log.Printf("工作階段: 開始=%v, 結束=%v, 記錄數=%d, 進行中=%v",
+			session.Start.Format("15:04:05"),
+			session.End.Format("15:04:05"),
+			len(session.Segments),
+			session.InProgress)
+	}
+
+	cal := r.chart.tracker.Calendar()
+
+	// 休眠時段以深色底條表示，與單純沒有輸入的時間區分
+	gaps, err := r.chart.tracker.GetTodayGaps()
+	if err != nil {
+		log.Printf("獲取今日休眠時段失敗: %v", err)
+	}
+	for _, gap := range gaps {
+		if slice, ok := todaySlice(cal, r.chart.startTime, gap.Start, gap.End); ok {
+			r.drawBar(slice, innerPos, innerSize, 0, 1, gapColor)
 		}
-		log.Printf("活動: 開始=%v, 結束=%v, 類型=%v",
-			activity.StartTime().Format("15:04:05"),
-			endTimeStr,
-			activity.Type)
-	}
-
-	// 繪製活動時間條
-	for _, activity := range activities {
-		startX := r.timeToX(activity.StartTime(), innerSize.Width)
-		var endX float32
-
-		// 處理正在進行中的活動
-		if !activity.IsEnded() {
-			// 如果活動還在進行中，使用當前時間作為結束時間
-			if r.chart.tracker.IsActive() {
-				endX = r.timeToX(time.Now(), innerSize.Width)
-			} else {
-				// 如果活動已經停止但沒有結束時間，使用最後活動時間
-				endX = r.timeToX(r.chart.tracker.GetLastActivityTime(), innerSize.Width)
-			}
-		} else {
-			endX = r.timeToX(activity.EndTime(), innerSize.Width)
+	}
+
+	for _, session := range sessions {
+		// 跨日的工作階段只畫出落在今天的部分
+		slice, ok := todaySlice(cal, r.chart.startTime, session.Start, session.End)
+		if !ok {
+			continue
 		}
 
-		// 打印計算出的座標，用於調試
-		log.Printf("時間條座標: startX=%.2f, endX=%.2f", startX, endX)
+		// 工作階段以淡色底條表示，其中的短暫間隔也算在內
+		r.drawBar(slice, innerPos, innerSize, 0, 1, sessionColor)
 
-		if endX > startX { // 確保時間條有寬度
-			rect := canvas.NewRectangle(getActivityColor(activity.Type))
-			rect.Move(fyne.NewPos(
-				innerPos.X+startX,
-				innerPos.Y+5,
-			))
-			rect.Resize(fyne.NewSize(
-				endX-startX,
-				innerSize.Height-30,
-			))
+		// 在底條上繪製各類型的活動時間條，滑鼠在上半部、鍵盤在下半部，同時進行的活動不會互相遮住
+		for _, activity := range session.Segments {
+			endTime := activity.EndTime()
+			if !activity.IsEnded() {
+				// 進行中的活動畫到工作階段的結束時間
+				endTime = session.End
+			}
+
+			slice, ok := todaySlice(cal, r.chart.startTime, activity.StartTime(), endTime)
+			if !ok {
+				continue
+			}
 
 			// 添加半透明效果，進行中的活動使用不同的透明度
-			rectColor := rect.FillColor.(color.NRGBA)
+			rectColor := getActivityColor(activity.Type).(color.NRGBA)
 			if !activity.IsEnded() {
 				rectColor.A = 120 // 進行中的活動更透明
 			} else {
 				rectColor.A = 180
 			}
-			rect.FillColor = rectColor
+			if activity.Type == domain.ManualActivity {
+				// 手動輸入的時間不會與其他活動重疊，佔滿兩列
+				r.drawBar(slice, innerPos, innerSize, 0, 1, rectColor)
+			} else {
+				r.drawBar(slice, innerPos, innerSize, activityLane(activity.Type), 2, rectColor)
+			}
+		}
+	}
 
-			r.rects = append(r.rects, rect)
+	r.drawIntensity(innerPos, innerSize)
+	r.drawFocus(cal, innerPos, innerSize)
+}
+
+// drawFocus 在時間條下方以細條標示取得焦點的應用程式，同一個應用程式固定使用同一種顏色
+func (r *timelineRenderer) drawFocus(cal domain.Calendar, innerPos fyne.Position, innerSize fyne.Size) {
+	periods, err := r.chart.tracker.GetTodayFocus()
+	if err != nil {
+		log.Printf("獲取今日焦點時段失敗: %v", err)
+		return
+	}
+
+	for _, period := range periods {
+		slice, ok := todaySlice(cal, r.chart.startTime, period.Start, period.End)
+		if !ok {
+			continue
+		}
+
+		startX := r.timeToX(slice.Start, innerSize.Width)
+		endX := r.timeToX(slice.End, innerSize.Width)
+		if endX <= startX {
+			continue
 		}
+
+		rect := canvas.NewRectangle(appColor(period.App))
+		rect.Move(fyne.NewPos(innerPos.X+startX, innerPos.Y+innerSize.Height-25+1))
+		rect.Resize(fyne.NewSize(endX-startX, focusBarHeight))
+		r.rects = append(r.rects, rect)
 	}
 }
 
-func (r *timelineRenderer) timeToX(t time.Time, width float32) float32 {
-	// 將時間轉換為本地時間
-	t = t.In(time.Local)
+// drawIntensity 在各類型的列上依每分鐘的輸入強度加上陰影，越密集的輸入顏色越深
+func (r *timelineRenderer) drawIntensity(innerPos fyne.Position, innerSize fyne.Size) {
+	intensities, err := r.chart.intensity.Between(context.Background(), r.chart.startTime, r.chart.endTime)
+	if err != nil {
+		log.Printf("獲取輸入強度失敗: %v", err)
+		return
+	}
+
+	// 以今天最密集的一分鐘作為最深的顏色
+	var maxMouse, maxKeyboard float64
+	for _, intensity := range intensities {
+		maxMouse = max(maxMouse, mouseScore(intensity))
+		maxKeyboard = max(maxKeyboard, float64(intensity.KeyPresses))
+	}
+
+	for _, intensity := range intensities {
+		slice := domain.DaySlice{
+			DayStart: r.chart.startTime,
+			Start:    intensity.Minute,
+			End:      intensity.Minute.Add(time.Minute),
+		}
+		if score := mouseScore(intensity); score > 0 {
+			r.drawBar(slice, innerPos, innerSize, activityLane(domain.MouseActivity), 2, intensityColor(score/maxMouse))
+		}
+		if intensity.KeyPresses > 0 {
+			r.drawBar(slice, innerPos, innerSize, activityLane(domain.KeyboardActivity), 2, intensityColor(float64(intensity.KeyPresses)/maxKeyboard))
+		}
+	}
+}
 
-	// 只取時分秒部分
-	hour, min, sec := t.Clock()
+// drawBar 把時間條畫在繪圖區域平分成 lanes 列後的第 lane 列
+func (r *timelineRenderer) drawBar(slice domain.DaySlice, innerPos fyne.Position, innerSize fyne.Size, lane, lanes int, fill color.Color) {
+	startX := r.timeToX(slice.Start, innerSize.Width)
+	endX := r.timeToX(slice.End, innerSize.Width)
+	if endX <= startX { // 確保時間條有寬度
+		return
+	}
+
+	laneHeight := (innerSize.Height - 30) / float32(lanes)
+	rect := canvas.NewRectangle(fill)
+	rect.Move(fyne.NewPos(
+		innerPos.X+startX,
+		innerPos.Y+5+laneHeight*float32(lane),
+	))
+	rect.Resize(fyne.NewSize(
+		endX-startX,
+		laneHeight,
+	))
+	r.rects = append(r.rects, rect)
+}
 
-	// 計算從當天開始到現在的秒數
-	seconds := float64(hour*3600 + min*60 + sec)
-	totalSeconds := float64(24 * 60 * 60)
+func (r *timelineRenderer) timeToX(t time.Time, width float32) float32 {
+	// 計算從當天開始經過的秒數，超出今天範圍的部分截掉
+	if t.Before(r.chart.startTime) {
+		t = r.chart.startTime
+	}
+	if t.After(r.chart.endTime) {
+		t = r.chart.endTime
+	}
+
+	seconds := t.Sub(r.chart.startTime).Seconds()
+	totalSeconds := r.chart.endTime.Sub(r.chart.startTime).Seconds()
 	proportion := seconds / totalSeconds
 
 	return width * float32(proportion)
 }
 
+func todaySlice(cal domain.Calendar, today, start, end time.Time) (domain.DaySlice, bool) {
+	for _, slice := range cal.Split(start, end) {
+		if slice.DayStart.Equal(today) {
+			return slice, true
+		}
+	}
+	return domain.DaySlice{}, false
+}
+
+// sessionColor 是工作階段底條的顏色
+var sessionColor = color.NRGBA{R: 189, G: 195, B: 199, A: 90}
+
+// gapColor 是休眠時段底條的顏色
+var gapColor = color.NRGBA{R: 52, G: 73, B: 94, A: 70}
+
+// focusBarHeight 是應用程式細條的高度
+const focusBarHeight = 4
+
+// appPalette 是應用程式細條使用的顏色
+var appPalette = []color.NRGBA{
+	{R: 231, G: 76, B: 60, A: 200},   // 紅色
+	{R: 230, G: 126, B: 34, A: 200},  // 橘色
+	{R: 241, G: 196, B: 15, A: 200},  // 黃色
+	{R: 26, G: 188, B: 156, A: 200},  // 青色
+	{R: 155, G: 89, B: 182, A: 200},  // 紫色
+	{R: 52, G: 73, B: 94, A: 200},    // 深藍色
+	{R: 211, G: 84, B: 0, A: 200},    // 深橘色
+	{R: 127, G: 140, B: 141, A: 200}, // 灰色
+}
+
+// appColor 依應用程式名稱的雜湊值選擇顏色
+func appColor(app string) color.Color {
+	if app == "" {
+		return color.NRGBA{R: 220, G: 220, B: 220, A: 200}
+	}
+	h := fnv.New32a()
+	h.Write([]byte(app))
+	return appPalette[h.Sum32()%uint32(len(appPalette))]
+}
+
+// mouseDistancePerEvent 是滑鼠移動多少像素算作一次滑鼠輸入
+const mouseDistancePerEvent = 100
+
+func mouseScore(intensity domain.InputIntensity) float64 {
+	return float64(intensity.Clicks+intensity.Scrolls) + intensity.MouseDistance/mouseDistancePerEvent
+}
+
+// intensityColor 回傳比例為 ratio（0-1）的陰影顏色
+func intensityColor(ratio float64) color.Color {
+	return color.NRGBA{A: uint8(20 + 100*min(ratio, 1))}
+}
+
+// activityLane 回傳活動類型在時間軸上的列
+func activityLane(activityType domain.ActivityType) int {
+	if activityType == domain.MouseActivity {
+		return 0
+	}
+	return 1
+}
+
 func getActivityColor(activityType domain.ActivityType) color.Color {
 	switch activityType {
 	case domain.MouseActivity:
 		return color.NRGBA{R: 46, G: 204, B: 113, A: 255} // 綠色
 	case domain.KeyboardActivity:
 		return color.NRGBA{R: 52, G: 152, B: 219, A: 255} // 藍色
+	case domain.ManualActivity:
+		return color.NRGBA{R: 230, G: 126, B: 34, A: 255} // 橘色
 	default:
 		return color.Gray{Y: 128}
 	}
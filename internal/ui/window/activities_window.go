@@ -0,0 +1,406 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+)
+
+// ActivitiesWindow 列出某一天的活動，可手動新增、修改、分割、刪除與設定標籤，並復原或重做這些變更
+type ActivitiesWindow struct {
+	window   fyne.Window
+	editor   *usecase.ActivityEditor
+	projects *usecase.ProjectService
+	tracker  *usecase.ActivityTracker
+	onChange func()
+
+	day        time.Time // 顯示中那一天的開始時間
+	activities []domain.Activity
+	selected   int // 選取的列，-1 表示沒有選取
+}
+
+func NewActivitiesWindow(app fyne.App, editor *usecase.ActivityEditor, projects *usecase.ProjectService, tracker *usecase.ActivityTracker, onChange func()) *ActivitiesWindow {
+	window := app.NewWindow("活動記錄")
+	day, _ := tracker.Today()
+	return &ActivitiesWindow{
+		window:   window,
+		editor:   editor,
+		projects: projects,
+		tracker:  tracker,
+		onChange: onChange,
+		day:      day,
+		selected: -1,
+	}
+}
+
+func (w *ActivitiesWindow) Show() {
+	ctx := context.Background()
+
+	dayLabel := widget.NewLabel("")
+	list := widget.NewList(
+		func() int {
+			return len(w.activities)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("00:00:00 - 00:00:00")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(w.formatActivity(ctx, w.activities[i]))
+		},
+	)
+	list.OnSelected = func(i widget.ListItemID) {
+		w.selected = i
+	}
+	list.OnUnselected = func(widget.ListItemID) {
+		w.selected = -1
+	}
+
+	reload := func() {
+		activities, err := w.editor.ActivitiesOn(ctx, w.day)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.activities = activities
+		w.selected = -1
+		list.UnselectAll()
+		list.Refresh()
+		dayLabel.SetText(w.day.Format("2006-01-02"))
+	}
+	changed := func() {
+		reload()
+		if w.onChange != nil {
+			w.onChange()
+		}
+	}
+
+	cal := w.tracker.Calendar()
+	prevBtn := widget.NewButton("前一天", func() {
+		w.day = cal.DayStart(w.day.Add(-time.Hour))
+		reload()
+	})
+	nextBtn := widget.NewButton("後一天", func() {
+		w.day = cal.NextDayStart(w.day)
+		reload()
+	})
+
+	addBtn := widget.NewButton("新增", func() {
+		activity := domain.Activity{Type: domain.ManualActivity, ProjectID: w.tracker.CurrentProject()}
+		w.showActivityForm("新增活動", activity, func(activity domain.Activity) error {
+			_, err := w.editor.Add(ctx, activity)
+			return err
+		}, changed)
+	})
+	editBtn := widget.NewButton("編輯", func() {
+		activity, ok := w.selectedActivity()
+		if !ok {
+			return
+		}
+		w.showActivityForm("編輯活動", activity, func(activity domain.Activity) error {
+			return w.editor.Edit(ctx, activity)
+		}, changed)
+	})
+	splitBtn := widget.NewButton("分割", func() {
+		activity, ok := w.selectedActivity()
+		if !ok {
+			return
+		}
+		w.showSplitForm(activity, changed)
+	})
+	tagsBtn := widget.NewButton("標籤", func() {
+		activity, ok := w.selectedActivity()
+		if !ok {
+			return
+		}
+		w.showTagsForm(activity, changed)
+	})
+	deleteBtn := widget.NewButton("刪除", func() {
+		activity, ok := w.selectedActivity()
+		if !ok {
+			return
+		}
+		dialog.ShowConfirm("刪除活動", fmt.Sprintf("確定要刪除 %s？", w.formatActivity(ctx, activity)), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := w.editor.Delete(ctx, activity.ID); err != nil {
+				dialog.ShowError(err, w.window)
+				return
+			}
+			changed()
+		}, w.window)
+	})
+
+	undoBtn := widget.NewButton("復原", func() {
+		undoEdit(w.window, w.editor, changed)
+	})
+	redoBtn := widget.NewButton("重做", func() {
+		redoEdit(w.window, w.editor, changed)
+	})
+	addUndoShortcuts(w.window, w.editor, changed)
+
+	reload()
+
+	w.window.SetContent(container.NewBorder(
+		container.NewHBox(prevBtn, dayLabel, nextBtn),
+		container.NewHBox(addBtn, editBtn, splitBtn, tagsBtn, deleteBtn, undoBtn, redoBtn),
+		nil, nil,
+		list,
+	))
+	w.window.Resize(fyne.NewSize(480, 560))
+	w.window.Show()
+}
+
+func (w *ActivitiesWindow) selectedActivity() (domain.Activity, bool) {
+	if w.selected < 0 || w.selected >= len(w.activities) {
+		dialog.ShowInformation("活動記錄", "請先選擇一筆活動", w.window)
+		return domain.Activity{}, false
+	}
+	return w.activities[w.selected], true
+}
+
+// showActivityForm 顯示活動的時間、類型與專案，確認後以 save 保存
+func (w *ActivitiesWindow) showActivityForm(title string, activity domain.Activity, save func(domain.Activity) error, onSaved func()) {
+	ctx := context.Background()
+
+	startEntry := widget.NewEntry()
+	startEntry.SetPlaceHolder("15:04")
+	endEntry := widget.NewEntry()
+	endEntry.SetPlaceHolder("15:04")
+	if activity.ID != 0 {
+		startEntry.SetText(w.formatClock(activity.StartTime()))
+		if activity.IsEnded() {
+			endEntry.SetText(w.formatClock(activity.EndTime()))
+		}
+	}
+
+	typeSelect := widget.NewSelect(activityTypeLabels(), nil)
+	typeSelect.SetSelected(activityTypeLabel(activity.Type))
+
+	projects, err := w.projects.Projects(ctx)
+	if err != nil {
+		log.Printf("查詢專案失敗: %v", err)
+	}
+	projectOptions := []string{noProjectLabel}
+	projectSelected := noProjectLabel
+	for _, project := range projects {
+		projectOptions = append(projectOptions, project.Name)
+		if project.ID == activity.ProjectID {
+			projectSelected = project.Name
+		}
+	}
+	projectSelect := widget.NewSelect(projectOptions, nil)
+	projectSelect.SetSelected(projectSelected)
+
+	dialog.ShowForm(title, "儲存", "取消", []*widget.FormItem{
+		widget.NewFormItem("開始", startEntry),
+		widget.NewFormItem("結束", endEntry),
+		widget.NewFormItem("類型", typeSelect),
+		widget.NewFormItem("專案", projectSelect),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		start, err := w.parseClock(startEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		end, err := w.parseClock(endEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+
+		activity.SetStartTime(start)
+		activity.SetEndTime(end)
+		for _, activityType := range activityTypes {
+			if activityTypeLabel(activityType) == typeSelect.Selected {
+				activity.Type = activityType
+			}
+		}
+		activity.ProjectID = 0
+		for _, project := range projects {
+			if project.Name == projectSelect.Selected {
+				activity.ProjectID = project.ID
+			}
+		}
+
+		if err := save(activity); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		onSaved()
+	}, w.window)
+}
+
+func (w *ActivitiesWindow) showSplitForm(activity domain.Activity, onSaved func()) {
+	atEntry := widget.NewEntry()
+	atEntry.SetPlaceHolder("15:04")
+
+	dialog.ShowForm("分割活動", "分割", "取消", []*widget.FormItem{
+		widget.NewFormItem("分割時間", atEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		at, err := w.parseClock(atEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		if _, _, err := w.editor.Split(context.Background(), activity.ID, at); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		onSaved()
+	}, w.window)
+}
+
+// showTagsForm 勾選活動的標籤，也可以新增標籤，確認後由 editor 保存以便復原
+func (w *ActivitiesWindow) showTagsForm(activity domain.Activity, onSaved func()) {
+	ctx := context.Background()
+
+	tags, err := w.projects.Tags(ctx)
+	if err != nil {
+		dialog.ShowError(err, w.window)
+		return
+	}
+	current, err := w.projects.ActivityTags(ctx, activity.ID)
+	if err != nil {
+		dialog.ShowError(err, w.window)
+		return
+	}
+
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	checks := widget.NewCheckGroup(names, nil)
+	selected := make([]string, len(current))
+	for i, tag := range current {
+		selected[i] = tag.Name
+	}
+	checks.SetSelected(selected)
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("新的標籤")
+	createBtn := widget.NewButton("新增標籤", func() {
+		tag, err := w.projects.CreateTag(ctx, nameEntry.Text)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		tags = append(tags, tag)
+		checks.Append(tag.Name)
+		checks.SetSelected(append(checks.Selected, tag.Name))
+		nameEntry.SetText("")
+	})
+
+	content := container.NewBorder(nil, container.NewBorder(nil, nil, nil, createBtn, nameEntry), nil, nil,
+		container.NewVScroll(checks))
+	form := dialog.NewCustomConfirm("標籤："+w.formatActivity(ctx, activity), "儲存", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+
+		var tagIDs []int64
+		for _, tag := range tags {
+			if slices.Contains(checks.Selected, tag.Name) {
+				tagIDs = append(tagIDs, tag.ID)
+			}
+		}
+		if err := w.editor.SetTags(ctx, activity.ID, tagIDs); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		onSaved()
+	}, w.window)
+	form.Resize(fyne.NewSize(360, 320))
+	form.Show()
+}
+
+// parseClock 將 "15:04" 或 "15:04:05" 解析成顯示中那一天的時間，
+// 早於每日開始時間的時間算在隔天
+func (w *ActivitiesWindow) parseClock(text string) (time.Time, error) {
+	var clock time.Time
+	var err error
+	for _, layout := range []string{time.TimeOnly, "15:04"} {
+		if clock, err = time.Parse(layout, text); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("時間格式錯誤 %q，應為 HH:MM 或 HH:MM:SS", text)
+	}
+
+	day := w.tracker.Calendar().In(w.day)
+	t := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, day.Location())
+	if t.Before(w.day) {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func (w *ActivitiesWindow) formatClock(t time.Time) string {
+	return w.tracker.Calendar().In(t).Format(time.TimeOnly)
+}
+
+func (w *ActivitiesWindow) formatActivity(ctx context.Context, activity domain.Activity) string {
+	end := "進行中"
+	if activity.IsEnded() {
+		end = w.formatClock(activity.EndTime())
+	}
+
+	text := fmt.Sprintf("%s - %s  %s", w.formatClock(activity.StartTime()), end, activityTypeLabel(activity.Type))
+	if activity.ProjectID != 0 {
+		if project, err := w.projects.Project(ctx, activity.ProjectID); err == nil {
+			text += "  " + project.Name
+		}
+	}
+	if tags, err := w.projects.ActivityTags(ctx, activity.ID); err == nil {
+		for _, tag := range tags {
+			text += "  #" + tag.Name
+		}
+	}
+	if activity.Manual {
+		text += "  （手動）"
+	}
+	return text
+}
+
+// activityTypes 是可以手動選擇的活動類型
+var activityTypes = []domain.ActivityType{domain.ManualActivity, domain.MouseActivity, domain.KeyboardActivity}
+
+func activityTypeLabel(activityType domain.ActivityType) string {
+	switch activityType {
+	case domain.MouseActivity:
+		return "滑鼠"
+	case domain.KeyboardActivity:
+		return "鍵盤"
+	case domain.ManualActivity:
+		return "手動輸入"
+	default:
+		return string(activityType)
+	}
+}
+
+func activityTypeLabels() []string {
+	labels := make([]string, len(activityTypes))
+	for i, activityType := range activityTypes {
+		labels[i] = activityTypeLabel(activityType)
+	}
+	return labels
+}
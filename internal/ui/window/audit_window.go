@@ -0,0 +1,192 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"main/internal/domain"
+	"main/internal/usecase"
+)
+
+// auditDefaultDays 是稽核記錄預設顯示的天數
+const auditDefaultDays = 7
+
+// AuditWindow 顯示一段期間內的稽核記錄，可匯出成 CSV，並從刪除的記錄還原活動
+type AuditWindow struct {
+	window   fyne.Window
+	audit    *usecase.AuditService
+	editor   *usecase.ActivityEditor
+	tracker  *usecase.ActivityTracker
+	onChange func()
+	entries  []domain.AuditEntry
+	selected int // 選取的記錄，-1 表示沒有選取
+}
+
+func NewAuditWindow(app fyne.App, audit *usecase.AuditService, editor *usecase.ActivityEditor, tracker *usecase.ActivityTracker, onChange func()) *AuditWindow {
+	window := app.NewWindow("稽核記錄")
+	return &AuditWindow{
+		window:   window,
+		audit:    audit,
+		editor:   editor,
+		tracker:  tracker,
+		onChange: onChange,
+		selected: -1,
+	}
+}
+
+func (w *AuditWindow) Show() {
+	ctx := context.Background()
+	cal := w.tracker.Calendar()
+	loc := cal.In(time.Time{}).Location()
+
+	today, _ := w.tracker.Today()
+	fromEntry := widget.NewEntry()
+	fromEntry.SetText(cal.In(today.AddDate(0, 0, -auditDefaultDays+1)).Format(time.DateOnly))
+	toEntry := widget.NewEntry()
+	toEntry.SetText(cal.In(today).Format(time.DateOnly))
+
+	headers := []string{"時間", "來源", "操作", "活動 ID", "變更前", "變更後", "說明"}
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(w.entries) + 1, len(headers)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("0000-00-00 00:00:00")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			if i.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(headers[i.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			label.SetText(auditCell(w.entries[i.Row-1], i.Col, loc))
+		},
+	)
+	for col, width := range []float32{150, 80, 60, 60, 300, 300, 300} {
+		table.SetColumnWidth(col, width)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		w.selected = id.Row - 1
+	}
+	table.OnUnselected = func(widget.TableCellID) {
+		w.selected = -1
+	}
+
+	// parseRange 將輸入的日期轉成 [from, to)，兩端都包含整天
+	parseRange := func() (time.Time, time.Time, error) {
+		from, err := time.ParseInLocation(time.DateOnly, fromEntry.Text, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("開始日期格式錯誤，應為 YYYY-MM-DD")
+		}
+		to, err := time.ParseInLocation(time.DateOnly, toEntry.Text, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("結束日期格式錯誤，應為 YYYY-MM-DD")
+		}
+		return cal.DayStart(from.Add(time.Duration(cal.DayStartHour) * time.Hour)),
+			cal.NextDayStart(cal.DayStart(to.Add(time.Duration(cal.DayStartHour) * time.Hour))), nil
+	}
+
+	queryBtn := widget.NewButton("查詢", func() {
+		from, to, err := parseRange()
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		entries, err := w.audit.Entries(ctx, from, to)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.entries = entries
+		w.selected = -1
+		table.UnselectAll()
+		table.Refresh()
+	})
+
+	// 重新啟動後無法復原的刪除可以從稽核記錄還原
+	restoreBtn := widget.NewButton("還原刪除的活動", func() {
+		if w.selected < 0 || w.selected >= len(w.entries) || w.entries[w.selected].Operation != domain.AuditDelete {
+			dialog.ShowInformation("稽核記錄", "請先選擇一筆刪除活動的記錄", w.window)
+			return
+		}
+		restored, err := w.editor.RestoreDeleted(ctx, w.entries[w.selected])
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		if w.onChange != nil {
+			w.onChange()
+		}
+		queryBtn.OnTapped()
+		dialog.ShowInformation("稽核記錄", fmt.Sprintf("已還原活動，新的 ID=%d", restored.ID), w.window)
+	})
+
+	exportBtn := widget.NewButton("匯出 CSV", func() {
+		from, to, err := parseRange()
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil {
+				dialog.ShowError(err, w.window)
+				return
+			}
+			if writer == nil {
+				return // 使用者取消
+			}
+			defer writer.Close()
+
+			if err := w.audit.ExportCSV(ctx, writer, from, to, loc); err != nil {
+				dialog.ShowError(err, w.window)
+			}
+		}, w.window)
+		save.SetFileName(fmt.Sprintf("workpulse-audit-%s-%s.csv", fromEntry.Text, toEntry.Text))
+		save.Show()
+	})
+
+	queryBtn.OnTapped()
+
+	w.window.SetContent(container.NewBorder(
+		container.NewHBox(
+			widget.NewLabel("從"), fromEntry,
+			widget.NewLabel("到"), toEntry,
+			queryBtn, exportBtn, restoreBtn,
+		),
+		nil, nil, nil,
+		table,
+	))
+	w.window.Resize(fyne.NewSize(900, 560))
+	w.window.Show()
+}
+
+func auditCell(entry domain.AuditEntry, col int, loc *time.Location) string {
+	switch col {
+	case 0:
+		return entry.Time.In(loc).Format(time.DateTime)
+	case 1:
+		return string(entry.Source)
+	case 2:
+		return string(entry.Operation)
+	case 3:
+		if entry.ActivityID == 0 {
+			return ""
+		}
+		return strconv.FormatInt(entry.ActivityID, 10)
+	case 4:
+		return usecase.FormatAuditActivity(entry.Before, loc)
+	case 5:
+		return usecase.FormatAuditActivity(entry.After, loc)
+	default:
+		return entry.Detail
+	}
+}
@@ -1,38 +1,69 @@
 package window
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
+	"main/internal/domain"
 	"main/internal/ui/component"
 	"main/internal/usecase"
+	"main/pkg/clock"
 	"main/pkg/utils"
-	"time"
 )
 
 type MainWindow struct {
-	window   fyne.Window
-	app      fyne.App
-	tracker  *usecase.ActivityTracker
-	settings *usecase.SettingsManager
+	window    fyne.Window
+	app       fyne.App
+	tracker   *usecase.ActivityTracker
+	intensity *usecase.IntensityRecorder
+	privacy   *usecase.PrivacyFilter
+	rules     *usecase.RuleEngine
+	projects  *usecase.ProjectService
+	editor    *usecase.ActivityEditor
+	audit     *usecase.AuditService
+	settings  *usecase.SettingsManager
+	clock     clock.Clock
+
+	statsMu      sync.Mutex
+	stats        []domain.DailyStats // 每次刷新時更新一次，避免每個表格欄位都重新計算
+	projectNames map[int64]string    // 統計中顯示的專案名稱，重新載入專案選單時更新
+
+	reloadProjects func() // 重新載入專案選單，復原切換專案後顯示目前的專案
 }
 
-func NewMainWindow(app fyne.App, tracker *usecase.ActivityTracker, settings *usecase.SettingsManager) *MainWindow {
+func NewMainWindow(app fyne.App, tracker *usecase.ActivityTracker, intensity *usecase.IntensityRecorder, privacy *usecase.PrivacyFilter, rules *usecase.RuleEngine, projects *usecase.ProjectService, editor *usecase.ActivityEditor, audit *usecase.AuditService, settings *usecase.SettingsManager, clk clock.Clock) *MainWindow {
 	window := app.NewWindow("Work Pulse")
 	return &MainWindow{
-		window:   window,
-		app:      app,
-		tracker:  tracker,
-		settings: settings,
+		window:    window,
+		app:       app,
+		tracker:   tracker,
+		intensity: intensity,
+		privacy:   privacy,
+		rules:     rules,
+		projects:  projects,
+		editor:    editor,
+		audit:     audit,
+		settings:  settings,
+		clock:     clk,
 	}
 }
 
 func (w *MainWindow) Show() {
+	w.refreshStats()
+
 	// 創建表格
 	table := widget.NewTable(
 		func() (int, int) {
-			return len(w.tracker.GetDailyStats()) + 1, 4 // +1 for header row
+			return len(w.currentStats()) + 1, 7 // +1 for header row
 		},
 		func() fyne.CanvasObject {
 			return widget.NewLabel("000000000000")
@@ -40,12 +71,12 @@ func (w *MainWindow) Show() {
 		func(i widget.TableCellID, o fyne.CanvasObject) {
 			label := o.(*widget.Label)
 			if i.Row == 0 {
-				headers := []string{"日期", "總時間", "滑鼠時間", "鍵盤時間"}
+				headers := []string{"日期", "總時間", "滑鼠時間", "鍵盤時間", "手動時間", "專案", "應用程式"}
 				label.SetText(headers[i.Col])
 				// 設置表頭樣式
 				label.TextStyle = fyne.TextStyle{Bold: true}
 			} else {
-				stats := w.tracker.GetDailyStats()
+				stats := w.currentStats()
 				if i.Row-1 < len(stats) {
 					stat := stats[i.Row-1]
 					switch i.Col {
@@ -57,6 +88,12 @@ func (w *MainWindow) Show() {
 						label.SetText(utils.FormatDuration(stat.MouseDuration))
 					case 3:
 						label.SetText(utils.FormatDuration(stat.KeyboardDuration))
+					case 4:
+						label.SetText(utils.FormatDuration(stat.ManualDuration))
+					case 5:
+						label.SetText(formatTopProjects(stat.Projects, w.currentProjectNames()))
+					case 6:
+						label.SetText(formatTopApps(stat.Apps))
 					}
 				}
 			}
@@ -68,41 +105,104 @@ func (w *MainWindow) Show() {
 	table.SetColumnWidth(1, 100) // 總時間列
 	table.SetColumnWidth(2, 100) // 滑鼠時間列
 	table.SetColumnWidth(3, 100) // 鍵盤時間列
+	table.SetColumnWidth(4, 100) // 手動時間列
+	table.SetColumnWidth(5, 240) // 專案列
+	table.SetColumnWidth(6, 360) // 應用程式列
 
 	// 創建一個固定高度的滾動容器
 	tableContainer := container.NewVScroll(table)
 	tableContainer.SetMinSize(fyne.NewSize(500, 300)) // 設置最小大小
 
+	// 今日工作階段摘要
+	sessionLabel := widget.NewLabel("")
+	w.refreshSessionSummary(sessionLabel)
+
 	// 創建時間軸圖表
-	timeline := component.NewTimelineChart(w.tracker)
+	timeline := component.NewTimelineChart(w.tracker, w.intensity, w.clock)
 
 	// 添加設定按鈕
 	settingsBtn := widget.NewButton("設定", func() {
 		settingsWindow := NewSettingsWindow(w.app, w.settings, func() {
 			// 當設定更新時，重新載入設定
 			w.tracker.UpdateThreshold(w.settings.GetSettings().ThresholdSeconds)
+			w.tracker.UpdateTypeThreshold(domain.MouseActivity, w.settings.GetSettings().MouseThresholdSeconds)
+			w.tracker.UpdateTypeThreshold(domain.KeyboardActivity, w.settings.GetSettings().KeyboardThresholdSeconds)
+			w.tracker.UpdateSessionGap(w.settings.GetSettings().SessionGapSeconds)
+			w.tracker.UpdateHideWindowTitles(w.settings.GetSettings().HideWindowTitles)
+			w.privacy.Update(w.settings.GetSettings().TrackedInputKinds)
+			if cal, err := w.settings.GetSettings().Calendar(); err == nil {
+				if err := w.tracker.UpdateCalendar(cal); err != nil {
+					log.Printf("更新每日邊界時發生錯誤: %v", err)
+				}
+			}
 		})
 		settingsWindow.Show()
 	})
 
+	// 重新載入規則檔並套用到所有記錄，規則可能建立新的專案
+	rulesBtn := widget.NewButton("套用規則", func() {
+		ctx := context.Background()
+		if err := w.rules.Load(ctx); err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.reloadProjects()
+		changed, err := w.tracker.ApplyRules(ctx)
+		if err != nil {
+			dialog.ShowError(err, w.window)
+			return
+		}
+		w.refreshStats()
+		table.Refresh()
+		dialog.ShowInformation("套用規則", fmt.Sprintf("已重新歸類 %d 筆活動\n規則檔: %s", changed, w.rules.Path()), w.window)
+	})
+
+	// 手動變更活動或復原後立即更新統計、時間軸與目前的專案
+	changed := func() {
+		w.refreshStats()
+		table.Refresh()
+		w.refreshSessionSummary(sessionLabel)
+		timeline.Refresh()
+		w.reloadProjects()
+	}
+
+	activitiesBtn := widget.NewButton("活動記錄", func() {
+		NewActivitiesWindow(w.app, w.editor, w.projects, w.tracker, changed).Show()
+	})
+
+	auditBtn := widget.NewButton("稽核記錄", func() {
+		NewAuditWindow(w.app, w.audit, w.editor, w.tracker, changed).Show()
+	})
+
 	content := container.NewVBox(
 		container.NewHBox(
 			widget.NewLabel("工作時間追蹤"),
 			settingsBtn,
+			rulesBtn,
+			activitiesBtn,
+			auditBtn,
+			widget.NewLabel("目前專案："),
+			w.newProjectSelect(),
 		),
 		tableContainer, // 使用包裝後的表格容器
 		widget.NewLabel("今日活動時間軸"),
+		sessionLabel,
 		timeline,
 	)
 
 	w.window.SetContent(content)
 	w.window.Resize(fyne.NewSize(800, 600))
 
+	// Ctrl+Z 與 Ctrl+Shift+Z 復原或重做在活動記錄中的變更與專案的切換
+	addUndoShortcuts(w.window, w.editor, changed)
+
 	// 定期更新 UI
 	go func() {
 		ticker := time.NewTicker(time.Second)
 		for range ticker.C {
+			w.refreshStats()
 			table.Refresh()
+			w.refreshSessionSummary(sessionLabel)
 			timeline.Refresh()
 		}
 	}()
@@ -110,4 +210,157 @@ func (w *MainWindow) Show() {
 	w.window.ShowAndRun()
 }
 
+// noProjectLabel 是專案選單中表示沒有專案的選項
+const noProjectLabel = "（無專案）"
+
+// newProjectSelect 建立選擇目前專案的選單與新增專案的按鈕，選擇的專案由 ProjectService 保存到設定
+func (w *MainWindow) newProjectSelect() fyne.CanvasObject {
+	ctx := context.Background()
+
+	var projects []domain.Project
+	projectSelect := widget.NewSelect(nil, nil)
+	reload := func() {
+		var err error
+		projects, err = w.projects.Projects(ctx)
+		if err != nil {
+			log.Printf("查詢專案失敗: %v", err)
+		}
+
+		options := []string{noProjectLabel}
+		selected := noProjectLabel
+		current := w.tracker.CurrentProject()
+		names := make(map[int64]string, len(projects))
+		for _, project := range projects {
+			options = append(options, project.Name)
+			names[project.ID] = project.Name
+			if project.ID == current {
+				selected = project.Name
+			}
+		}
+		w.statsMu.Lock()
+		w.projectNames = names
+		w.statsMu.Unlock()
+		projectSelect.SetOptions(options)
+		projectSelect.SetSelected(selected)
+	}
+
+	projectSelect.OnChanged = func(name string) {
+		var projectID int64
+		for _, project := range projects {
+			if project.Name == name {
+				projectID = project.ID
+			}
+		}
+		if projectID == w.tracker.CurrentProject() {
+			return
+		}
+
+		if err := w.projects.SetCurrentProject(ctx, projectID); err != nil {
+			dialog.ShowError(err, w.window)
+			reload()
+		}
+	}
+
+	addBtn := widget.NewButton("新增專案", func() {
+		nameEntry := widget.NewEntry()
+		dialog.ShowForm("新增專案", "新增", "取消", []*widget.FormItem{
+			widget.NewFormItem("名稱", nameEntry),
+		}, func(ok bool) {
+			if !ok {
+				return
+			}
+			project, err := w.projects.CreateProject(ctx, nameEntry.Text)
+			if err != nil {
+				dialog.ShowError(err, w.window)
+				return
+			}
+			reload()
+			projectSelect.SetSelected(project.Name)
+		}, w.window)
+	})
+
+	reload()
+	w.reloadProjects = reload
+	return container.NewHBox(projectSelect, addBtn)
+}
+
+func (w *MainWindow) refreshStats() {
+	stats := w.tracker.GetDailyStats()
+
+	w.statsMu.Lock()
+	w.stats = stats
+	w.statsMu.Unlock()
+}
+
+func (w *MainWindow) currentStats() []domain.DailyStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	return w.stats
+}
+
+func (w *MainWindow) currentProjectNames() map[int64]string {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	return w.projectNames
+}
+
+// refreshSessionSummary 顯示今天的工作階段數量、總時間與最長的工作階段
+func (w *MainWindow) refreshSessionSummary(label *widget.Label) {
+	sessions, err := w.tracker.GetTodaySessions()
+	if err != nil {
+		log.Printf("獲取今日工作階段失敗: %v", err)
+		return
+	}
+
+	var total, longest time.Duration
+	for _, session := range sessions {
+		total += session.Duration()
+		if session.Duration() > longest {
+			longest = session.Duration()
+		}
+	}
+
+	label.SetText(fmt.Sprintf("工作階段: %d 段，共 %s，最長 %s",
+		len(sessions),
+		utils.FormatDuration(total),
+		utils.FormatDuration(longest)))
+}
+
+// topAppsShown 是表格中每天顯示的應用程式與專案數量
+const topAppsShown = 3
+
+// formatTopApps 列出活動時間最長的幾個應用程式，Apps 已依時間由長到短排序
+func formatTopApps(apps []domain.AppStats) string {
+	parts := make([]string, 0, topAppsShown)
+	for _, app := range apps {
+		if len(parts) == topAppsShown {
+			break
+		}
+		name := app.App
+		if name == "" {
+			name = "其他"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", name, utils.FormatDuration(app.Duration)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatTopProjects 列出活動時間最長的幾個專案，Projects 已依時間由長到短排序，names 是各專案的名稱
+func formatTopProjects(projects []domain.ProjectStats, names map[int64]string) string {
+	parts := make([]string, 0, topAppsShown)
+	for _, project := range projects {
+		if len(parts) == topAppsShown {
+			break
+		}
+		name := names[project.ProjectID]
+		if project.ProjectID == 0 {
+			name = "未分類"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", name, utils.FormatDuration(project.Duration)))
+	}
+	return strings.Join(parts, ", ")
+}
+
 // ... 實現其他方法 ...
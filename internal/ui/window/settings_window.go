@@ -1,6 +1,7 @@
 package window
 
 import (
+	"slices"
 	"strconv"
 
 	"fyne.io/fyne/v2"
@@ -32,6 +33,42 @@ func (w *SettingsWindow) Show() {
 	thresholdEntry := widget.NewEntry()
 	thresholdEntry.SetText(strconv.Itoa(currentSettings.ThresholdSeconds))
 
+	mouseThresholdEntry := widget.NewEntry()
+	mouseThresholdEntry.SetPlaceHolder("使用預設閾值")
+	mouseThresholdEntry.SetText(optionalInt(currentSettings.MouseThresholdSeconds))
+
+	keyboardThresholdEntry := widget.NewEntry()
+	keyboardThresholdEntry.SetPlaceHolder("使用預設閾值")
+	keyboardThresholdEntry.SetText(optionalInt(currentSettings.KeyboardThresholdSeconds))
+
+	// 追蹤的事件種類，全部勾選時存成 nil，之後新增的種類也會被追蹤；全部不勾選時不追蹤任何輸入
+	kindGroup := widget.NewCheckGroup(inputKindLabels(domain.InputKinds), nil)
+	kindGroup.Horizontal = true
+	if currentSettings.TrackedInputKinds == nil {
+		kindGroup.SetSelected(inputKindLabels(domain.InputKinds))
+	} else {
+		kindGroup.SetSelected(inputKindLabels(currentSettings.TrackedInputKinds))
+	}
+
+	hideTitlesCheck := widget.NewCheck("不記錄視窗標題（只記錄應用程式）", nil)
+	hideTitlesCheck.SetChecked(currentSettings.HideWindowTitles)
+
+	timezoneEntry := widget.NewEntry()
+	timezoneEntry.SetPlaceHolder("系統時區")
+	timezoneEntry.SetText(currentSettings.Timezone)
+
+	dayStartEntry := widget.NewEntry()
+	dayStartEntry.SetText(strconv.Itoa(currentSettings.DayStartHour))
+
+	sessionGapEntry := widget.NewEntry()
+	sessionGapEntry.SetText(strconv.Itoa(currentSettings.SessionGapSeconds))
+
+	compactEntry := widget.NewEntry()
+	compactEntry.SetText(strconv.Itoa(currentSettings.Retention.CompactAfterDays))
+
+	purgeEntry := widget.NewEntry()
+	purgeEntry.SetText(strconv.Itoa(currentSettings.Retention.PurgeAfterMonths))
+
 	saveBtn := widget.NewButton("儲存", func() {
 		threshold, err := strconv.Atoi(thresholdEntry.Text)
 		if err != nil {
@@ -39,8 +76,66 @@ func (w *SettingsWindow) Show() {
 			return
 		}
 
-		newSettings := domain.Settings{
-			ThresholdSeconds: threshold,
+		mouseThreshold, err := parseOptionalInt(mouseThresholdEntry.Text)
+		if err != nil || mouseThreshold < 0 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		keyboardThreshold, err := parseOptionalInt(keyboardThresholdEntry.Text)
+		if err != nil || keyboardThreshold < 0 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		dayStartHour, err := strconv.Atoi(dayStartEntry.Text)
+		if err != nil || dayStartHour < 0 || dayStartHour > 23 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		sessionGapSeconds, err := strconv.Atoi(sessionGapEntry.Text)
+		if err != nil || sessionGapSeconds < 0 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		compactAfterDays, err := strconv.Atoi(compactEntry.Text)
+		if err != nil || compactAfterDays < 0 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		purgeAfterMonths, err := strconv.Atoi(purgeEntry.Text)
+		if err != nil || purgeAfterMonths < 0 {
+			// TODO: 顯示錯誤訊息
+			return
+		}
+
+		newSettings := currentSettings
+		newSettings.ThresholdSeconds = threshold
+		newSettings.MouseThresholdSeconds = mouseThreshold
+		newSettings.KeyboardThresholdSeconds = keyboardThreshold
+		newSettings.HideWindowTitles = hideTitlesCheck.Checked
+		newSettings.Timezone = timezoneEntry.Text
+		newSettings.DayStartHour = dayStartHour
+		newSettings.SessionGapSeconds = sessionGapSeconds
+		newSettings.Retention.CompactAfterDays = compactAfterDays
+		newSettings.Retention.PurgeAfterMonths = purgeAfterMonths
+
+		newSettings.TrackedInputKinds = nil
+		if len(kindGroup.Selected) < len(domain.InputKinds) {
+			newSettings.TrackedInputKinds = make([]domain.InputKind, 0, len(kindGroup.Selected))
+			for _, kind := range domain.InputKinds {
+				if slices.Contains(kindGroup.Selected, inputKindLabel(kind)) {
+					newSettings.TrackedInputKinds = append(newSettings.TrackedInputKinds, kind)
+				}
+			}
+		}
+
+		if _, err := newSettings.Calendar(); err != nil {
+			// TODO: 顯示錯誤訊息
+			return
 		}
 
 		if err := w.settings.UpdateSettings(newSettings); err != nil {
@@ -57,10 +152,65 @@ func (w *SettingsWindow) Show() {
 	content := container.NewVBox(
 		widget.NewLabel("閾值設定（秒）："),
 		thresholdEntry,
+		widget.NewLabel("滑鼠閾值（秒，空白表示使用預設閾值）："),
+		mouseThresholdEntry,
+		widget.NewLabel("鍵盤閾值（秒，空白表示使用預設閾值）："),
+		keyboardThresholdEntry,
+		widget.NewLabel("追蹤的輸入事件（不記錄按下的按鍵）："),
+		kindGroup,
+		hideTitlesCheck,
+		widget.NewLabel("時區（例如 Asia/Taipei）："),
+		timezoneEntry,
+		widget.NewLabel("每日開始時間（0-23 點）："),
+		dayStartEntry,
+		widget.NewLabel("工作階段允許的最大間隔（秒）："),
+		sessionGapEntry,
+		widget.NewLabel("超過幾天的活動合併同一個工作階段的記錄（0 表示不合併）："),
+		compactEntry,
+		widget.NewLabel("超過幾個月的活動刪除（0 表示永久保留）："),
+		purgeEntry,
 		saveBtn,
 	)
 
 	w.window.SetContent(content)
-	w.window.Resize(fyne.NewSize(300, 200))
+	w.window.Resize(fyne.NewSize(360, 760))
 	w.window.Show()
-} 
\ No newline at end of file
+}
+
+func inputKindLabel(kind domain.InputKind) string {
+	switch kind {
+	case domain.InputKeyPress:
+		return "按鍵"
+	case domain.InputClick:
+		return "點擊"
+	case domain.InputScroll:
+		return "捲動"
+	case domain.InputMove:
+		return "移動"
+	default:
+		return string(kind)
+	}
+}
+
+func inputKindLabels(kinds []domain.InputKind) []string {
+	labels := make([]string, len(kinds))
+	for i, kind := range kinds {
+		labels[i] = inputKindLabel(kind)
+	}
+	return labels
+}
+
+// optionalInt 將 0 顯示為空白，用於可以留空的設定
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func parseOptionalInt(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(text)
+}
@@ -0,0 +1,52 @@
+package window
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+
+	"main/internal/usecase"
+)
+
+var (
+	undoShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault}
+	redoShortcut = &desktop.CustomShortcut{KeyName: fyne.KeyZ, Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift}
+)
+
+// addUndoShortcuts 在視窗加上 Ctrl+Z 復原與 Ctrl+Shift+Z 重做活動的變更，成功後呼叫 onChange
+func addUndoShortcuts(window fyne.Window, editor *usecase.ActivityEditor, onChange func()) {
+	window.Canvas().AddShortcut(undoShortcut, func(fyne.Shortcut) {
+		undoEdit(window, editor, onChange)
+	})
+	window.Canvas().AddShortcut(redoShortcut, func(fyne.Shortcut) {
+		redoEdit(window, editor, onChange)
+	})
+}
+
+func undoEdit(window fyne.Window, editor *usecase.ActivityEditor, onChange func()) {
+	label, err := editor.Undo(context.Background())
+	showUndoResult(window, "復原", label, err, usecase.ErrNothingToUndo, onChange)
+}
+
+func redoEdit(window fyne.Window, editor *usecase.ActivityEditor, onChange func()) {
+	label, err := editor.Redo(context.Background())
+	showUndoResult(window, "重做", label, err, usecase.ErrNothingToRedo, onChange)
+}
+
+// showUndoResult 在復原或重做失敗時顯示錯誤，沒有可操作的步驟時不打擾使用者
+func showUndoResult(window fyne.Window, action, label string, err, empty error, onChange func()) {
+	if errors.Is(err, empty) {
+		return
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("無法%s「%s」: %v", action, label, err), window)
+		return
+	}
+	if onChange != nil {
+		onChange()
+	}
+}
@@ -0,0 +1,410 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+	"main/pkg/clock"
+)
+
+var (
+	ErrActivityOverlap    = errors.New("活動時間與其他活動重疊")
+	ErrActivityInProgress = errors.New("無法修改進行中的活動")
+	ErrNotDeletion        = errors.New("只能還原刪除活動的稽核記錄")
+)
+
+// ActivityEditor 手動新增、修改、分割與刪除活動，變更過的活動都會標示為手動。
+// 同類型的活動不可重疊，手動輸入的活動不可與任何活動重疊，讓總時間維持各類型的聯集。
+// 每次變更都會記錄在復原歷程中，可用 Undo 與 Redo 復原或重做；
+// 復原歷程只保存在記憶體中，重新啟動後刪除的活動可用 RestoreDeleted 從稽核記錄還原。
+type ActivityEditor struct {
+	mu      sync.Mutex
+	repo    repository.ActivityRepository
+	tags    repository.ProjectRepository
+	tracker *ActivityTracker
+	clock   clock.Clock
+	history *undoHistory
+}
+
+func NewActivityEditor(repo repository.ActivityRepository, tags repository.ProjectRepository, tracker *ActivityTracker, clk clock.Clock) *ActivityEditor {
+	return &ActivityEditor{
+		repo:    repo,
+		tags:    tags,
+		tracker: tracker,
+		clock:   clk,
+		history: newUndoHistory(),
+	}
+}
+
+// ActivitiesOn 依開始時間順序回傳與 day 所屬那一天重疊的活動
+func (e *ActivityEditor) ActivitiesOn(ctx context.Context, day time.Time) ([]domain.Activity, error) {
+	start, end := e.tracker.Calendar().DayRange(day)
+
+	var activities []domain.Activity
+	for activity, err := range e.repo.GetActivitiesBetween(ctx, start, end) {
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
+
+// Add 新增一筆已結束的活動
+func (e *ActivityEditor) Add(ctx context.Context, activity domain.Activity) (domain.Activity, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	activity.ID = 0
+	activity.Manual = true
+	if err := e.validate(ctx, activity); err != nil {
+		return activity, err
+	}
+
+	saved, err := e.repo.Save(ctx, activity)
+	if err != nil {
+		return saved, err
+	}
+	e.tracker.InvalidateStats()
+
+	ref := e.history.ref(saved.ID)
+	removed := saved
+	var tagIDs []int64
+	e.history.push(undoAction{
+		label: "新增活動",
+		undo: func(ctx context.Context) (err error) {
+			removed, tagIDs, err = e.remove(ctx, ref, domain.AuditUndo)
+			return err
+		},
+		redo: func(ctx context.Context) error {
+			return e.restore(ctx, ref, removed, tagIDs)
+		},
+	})
+	return saved, nil
+}
+
+// Edit 修改已結束活動的時間、類型與專案
+func (e *ActivityEditor) Edit(ctx context.Context, activity domain.Activity) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous, err := e.endedActivity(ctx, activity.ID)
+	if err != nil {
+		return err
+	}
+	activity.Manual = true
+	if err := e.update(ctx, activity, domain.AuditManual); err != nil {
+		return err
+	}
+
+	label := "修改活動"
+	if activity.ProjectID != previous.ProjectID && activity.Type == previous.Type &&
+		activity.StartTimeUnix == previous.StartTimeUnix && activity.EndTimeUnix == previous.EndTimeUnix {
+		label = "變更專案"
+	}
+
+	ref := e.history.ref(activity.ID)
+	e.history.push(undoAction{
+		label: label,
+		undo: func(ctx context.Context) error {
+			previous.ID = ref.id
+			return e.update(ctx, previous, domain.AuditUndo)
+		},
+		redo: func(ctx context.Context) error {
+			activity.ID = ref.id
+			return e.update(ctx, activity, domain.AuditUndo)
+		},
+	})
+	return nil
+}
+
+// Split 在 at 將已結束的活動分成兩筆，回傳前後兩段
+func (e *ActivityEditor) Split(ctx context.Context, id int64, at time.Time) (domain.Activity, domain.Activity, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	original, err := e.endedActivity(ctx, id)
+	if err != nil {
+		return domain.Activity{}, domain.Activity{}, err
+	}
+	first, second, err := e.split(ctx, original, at, domain.AuditManual)
+	if err != nil {
+		return first, second, err
+	}
+
+	firstRef, secondRef := e.history.ref(first.ID), e.history.ref(second.ID)
+	e.history.push(undoAction{
+		label: "分割活動",
+		undo: func(ctx context.Context) error {
+			// 先刪除後半段，前半段延長回原本的範圍時才不會與它重疊
+			removed, tagIDs, err := e.remove(ctx, secondRef, domain.AuditUndo)
+			if err != nil {
+				return err
+			}
+			original.ID = firstRef.id
+			if err := e.update(ctx, original, domain.AuditUndo); err != nil {
+				if restoreErr := e.restore(ctx, secondRef, removed, tagIDs); restoreErr != nil {
+					log.Printf("復原分割失敗後還原後半段時發生錯誤: %v", restoreErr)
+				}
+				return err
+			}
+			return nil
+		},
+		redo: func(ctx context.Context) error {
+			original, err := e.endedActivity(ctx, firstRef.id)
+			if err != nil {
+				return err
+			}
+			_, second, err := e.split(ctx, original, at, domain.AuditUndo)
+			if err != nil {
+				return err
+			}
+			e.history.rename(secondRef, second.ID)
+			return nil
+		},
+	})
+	return first, second, nil
+}
+
+// Delete 刪除已結束的活動，刪除的活動可用 Undo 以新的 ID 還原
+func (e *ActivityEditor) Delete(ctx context.Context, id int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ref := e.history.ref(id)
+	removed, tagIDs, err := e.remove(ctx, ref, domain.AuditManual)
+	if err != nil {
+		return err
+	}
+
+	e.history.push(undoAction{
+		label: "刪除活動",
+		undo: func(ctx context.Context) error {
+			return e.restore(ctx, ref, removed, tagIDs)
+		},
+		redo: func(ctx context.Context) (err error) {
+			removed, tagIDs, err = e.remove(ctx, ref, domain.AuditUndo)
+			return err
+		},
+	})
+	return nil
+}
+
+// RestoreDeleted 依刪除活動的稽核記錄以新的 ID 還原活動與它的標籤，
+// 重新啟動或超過復原步驟上限後仍可還原。已經還原過的活動會因時間重疊而失敗。
+func (e *ActivityEditor) RestoreDeleted(ctx context.Context, entry domain.AuditEntry) (domain.Activity, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if entry.Operation != domain.AuditDelete || entry.Before == nil {
+		return domain.Activity{}, fmt.Errorf("%w: 稽核記錄 ID=%d", ErrNotDeletion, entry.ID)
+	}
+
+	// 還原後 ref 指向新的 ID
+	ref := &activityRef{}
+	removed, tagIDs := *entry.Before, entry.Tags
+	if err := e.restore(ctx, ref, removed, tagIDs); err != nil {
+		return domain.Activity{}, err
+	}
+
+	e.history.push(undoAction{
+		label: "還原刪除的活動",
+		undo: func(ctx context.Context) (err error) {
+			removed, tagIDs, err = e.remove(ctx, ref, domain.AuditUndo)
+			return err
+		},
+		redo: func(ctx context.Context) error {
+			return e.restore(ctx, ref, removed, tagIDs)
+		},
+	})
+	return e.repo.GetActivity(ctx, ref.id)
+}
+
+// SetTags 以 tagIDs 取代活動原本的標籤
+func (e *ActivityEditor) SetTags(ctx context.Context, id int64, tagIDs []int64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	previous, err := e.tagIDs(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := e.tags.SetActivityTags(ctx, id, tagIDs, domain.AuditManual); err != nil {
+		return err
+	}
+
+	ref := e.history.ref(id)
+	e.history.push(undoAction{
+		label: "變更標籤",
+		undo: func(ctx context.Context) error {
+			return e.tags.SetActivityTags(ctx, ref.id, previous, domain.AuditUndo)
+		},
+		redo: func(ctx context.Context) error {
+			return e.tags.SetActivityTags(ctx, ref.id, tagIDs, domain.AuditUndo)
+		},
+	})
+	return nil
+}
+
+// record 將其他使用案例的變更加入復原歷程，讓 Undo 與 Redo 依序處理所有變更
+func (e *ActivityEditor) record(label string, undo, redo func(ctx context.Context) error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.history.push(undoAction{label: label, undo: undo, redo: redo})
+}
+
+// Undo 復原最近一次變更並回傳它的名稱，沒有可復原的變更時回傳 ErrNothingToUndo
+func (e *ActivityEditor) Undo(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.history.undo(ctx)
+}
+
+// Redo 重做最近復原的變更並回傳它的名稱，沒有可重做的變更時回傳 ErrNothingToRedo
+func (e *ActivityEditor) Redo(ctx context.Context) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.history.redo(ctx)
+}
+
+// update 檢查後修改活動
+func (e *ActivityEditor) update(ctx context.Context, activity domain.Activity, source domain.AuditSource) error {
+	if _, err := e.endedActivity(ctx, activity.ID); err != nil {
+		return err
+	}
+	if err := e.validate(ctx, activity); err != nil {
+		return err
+	}
+
+	if err := e.repo.UpdateActivity(ctx, activity, source); err != nil {
+		return err
+	}
+	e.tracker.InvalidateStats()
+	return nil
+}
+
+// split 檢查分割時間後分割活動
+func (e *ActivityEditor) split(ctx context.Context, activity domain.Activity, at time.Time, source domain.AuditSource) (domain.Activity, domain.Activity, error) {
+	if !at.After(activity.StartTime()) || !at.Before(activity.EndTime()) {
+		return domain.Activity{}, domain.Activity{}, fmt.Errorf("分割時間必須在 %s 與 %s 之間",
+			activity.StartTime().Format(time.DateTime), activity.EndTime().Format(time.DateTime))
+	}
+
+	first, second, err := e.repo.SplitActivity(ctx, activity.ID, at, source)
+	if err != nil {
+		return first, second, err
+	}
+	e.tracker.InvalidateStats()
+	return first, second, nil
+}
+
+// remove 刪除 ref 指向的活動，回傳刪除前的活動與標籤供還原使用
+func (e *ActivityEditor) remove(ctx context.Context, ref *activityRef, source domain.AuditSource) (domain.Activity, []int64, error) {
+	activity, err := e.endedActivity(ctx, ref.id)
+	if err != nil {
+		return activity, nil, err
+	}
+	tagIDs, err := e.tagIDs(ctx, ref.id)
+	if err != nil {
+		return activity, nil, err
+	}
+
+	if err := e.repo.DeleteActivity(ctx, ref.id, source); err != nil {
+		return activity, nil, err
+	}
+	e.history.forget(ref)
+	e.tracker.InvalidateStats()
+	return activity, tagIDs, nil
+}
+
+// restore 重新加入 remove 刪除的活動，並讓 ref 指向新的 ID
+func (e *ActivityEditor) restore(ctx context.Context, ref *activityRef, activity domain.Activity, tagIDs []int64) error {
+	check := activity
+	check.ID = 0
+	if err := e.validate(ctx, check); err != nil {
+		return err
+	}
+
+	restored, err := e.repo.RestoreActivity(ctx, activity, tagIDs)
+	if err != nil {
+		return err
+	}
+	e.history.rename(ref, restored.ID)
+	e.tracker.InvalidateStats()
+	return nil
+}
+
+// tagIDs 回傳活動目前的標籤 ID
+func (e *ActivityEditor) tagIDs(ctx context.Context, id int64) ([]int64, error) {
+	tags, err := e.tags.GetActivityTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tagIDs := make([]int64, len(tags))
+	for i, tag := range tags {
+		tagIDs[i] = tag.ID
+	}
+	return tagIDs, nil
+}
+
+// endedActivity 讀取活動，進行中的活動由追蹤器管理，不可手動變更
+func (e *ActivityEditor) endedActivity(ctx context.Context, id int64) (domain.Activity, error) {
+	activity, err := e.repo.GetActivity(ctx, id)
+	if err != nil {
+		return activity, err
+	}
+	if !activity.IsEnded() {
+		return activity, fmt.Errorf("%w: ID=%d", ErrActivityInProgress, id)
+	}
+	return activity, nil
+}
+
+// validate 檢查活動的時間與類型，並確認不會與其他活動重疊
+func (e *ActivityEditor) validate(ctx context.Context, activity domain.Activity) error {
+	switch activity.Type {
+	case domain.MouseActivity, domain.KeyboardActivity, domain.ManualActivity:
+	default:
+		return fmt.Errorf("未知的活動類型: %q", activity.Type)
+	}
+
+	if !activity.IsEnded() || activity.EndTimeUnix <= activity.StartTimeUnix {
+		return fmt.Errorf("結束時間必須晚於開始時間")
+	}
+	now := e.clock.Now()
+	if activity.EndTime().After(now) {
+		return fmt.Errorf("結束時間不可晚於現在")
+	}
+
+	var types []domain.ActivityType
+	if activity.Type != domain.ManualActivity {
+		types = []domain.ActivityType{activity.Type, domain.ManualActivity}
+	}
+	for other, err := range e.repo.GetActivitiesBetween(ctx, activity.StartTime(), activity.EndTime(), types...) {
+		if err != nil {
+			return err
+		}
+		if other.ID == activity.ID || !activity.Overlaps(other, now) {
+			continue
+		}
+		return fmt.Errorf("%w: %s %s - %s", ErrActivityOverlap, other.Type,
+			other.StartTime().Format(time.TimeOnly), activityEnd(other, now).Format(time.TimeOnly))
+	}
+	return nil
+}
+
+// activityEnd 回傳活動的結束時間，進行中的活動算到 now 為止
+func activityEnd(activity domain.Activity, now time.Time) time.Time {
+	if activity.IsEnded() {
+		return activity.EndTime()
+	}
+	return now
+}
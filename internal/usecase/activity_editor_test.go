@@ -0,0 +1,240 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository/sqlite"
+	"main/internal/usecase"
+)
+
+func newTestEditor(env *testEnv) *usecase.ActivityEditor {
+	return usecase.NewActivityEditor(env.repo, sqlite.NewSQLiteProjectRepository(env.db, env.clock), env.tracker, env.clock)
+}
+
+// auditLog 回傳到目前為止的所有稽核記錄
+func (env *testEnv) auditLog(t *testing.T) []domain.AuditEntry {
+	t.Helper()
+
+	entries, err := sqlite.NewSQLiteAuditRepository(env.db).GetAuditLog(context.Background(), time.Unix(0, 0), env.clock.Now().Add(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func manualActivity(start, end time.Time) domain.Activity {
+	activity := domain.Activity{Type: domain.ManualActivity}
+	activity.SetStartTime(start)
+	activity.SetEndTime(end)
+	return activity
+}
+
+func TestActivityEditorUndoIsAuditedAsUndo(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+	editor := newTestEditor(env)
+	ctx := context.Background()
+
+	saved, err := editor.Add(ctx, manualActivity(start.Add(-2*time.Hour), start.Add(-time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := saved
+	edited.SetEndTime(start.Add(-30 * time.Minute))
+	if err := editor.Edit(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if _, err := editor.Undo(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := editor.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	type step struct {
+		source    domain.AuditSource
+		operation domain.AuditOperation
+	}
+	want := []step{
+		{domain.AuditManual, domain.AuditInsert}, // 新增
+		{domain.AuditManual, domain.AuditUpdate}, // 修改
+		{domain.AuditUndo, domain.AuditUpdate},   // 復原修改
+		{domain.AuditUndo, domain.AuditDelete},   // 復原新增
+		{domain.AuditUndo, domain.AuditInsert},   // 重做新增
+	}
+	var got []step
+	for _, entry := range env.auditLog(t) {
+		got = append(got, step{entry.Source, entry.Operation})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("稽核記錄 = %v，應為 %v", got, want)
+	}
+}
+
+func TestActivityEditorRestoreDeletedFromAudit(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+	ctx := context.Background()
+	projects := sqlite.NewSQLiteProjectRepository(env.db, env.clock)
+
+	tag, err := projects.CreateTag(ctx, "會議")
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor := newTestEditor(env)
+	saved, err := editor.Add(ctx, manualActivity(start.Add(-2*time.Hour), start.Add(-time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SetTags(ctx, saved.ID, []int64{tag.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.Delete(ctx, saved.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新啟動後復原歷程是空的，只能從稽核記錄還原
+	editor = newTestEditor(env)
+	if _, err := editor.Undo(ctx); !errors.Is(err, usecase.ErrNothingToUndo) {
+		t.Fatalf("重新啟動後 Undo = %v，應為 ErrNothingToUndo", err)
+	}
+
+	var deletion domain.AuditEntry
+	for _, entry := range env.auditLog(t) {
+		if entry.Operation == domain.AuditDelete {
+			deletion = entry
+		}
+	}
+	if _, err := editor.RestoreDeleted(ctx, env.auditLog(t)[0]); !errors.Is(err, usecase.ErrNotDeletion) {
+		t.Errorf("以新增的記錄還原 = %v，應為 ErrNotDeletion", err)
+	}
+
+	restored, err := editor.RestoreDeleted(ctx, deletion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID == saved.ID || restored.StartTimeUnix != saved.StartTimeUnix || restored.EndTimeUnix != saved.EndTimeUnix ||
+		restored.Type != saved.Type || !restored.Manual {
+		t.Errorf("還原的活動 = %+v，原本為 %+v", restored, saved)
+	}
+	tags, err := projects.GetActivityTags(ctx, restored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 1 || tags[0].ID != tag.ID {
+		t.Errorf("還原的標籤 = %+v，應為 %+v", tags, tag)
+	}
+
+	if _, err := editor.RestoreDeleted(ctx, deletion); !errors.Is(err, usecase.ErrActivityOverlap) {
+		t.Errorf("重複還原 = %v，應為 ErrActivityOverlap", err)
+	}
+
+	// 從稽核記錄還原也可以復原
+	if _, err := editor.Undo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.repo.GetActivity(ctx, restored.ID); err == nil {
+		t.Error("復原還原後活動應已刪除")
+	}
+}
+
+func TestActivityEditorUndoTagsAndCurrentProject(t *testing.T) {
+	start := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+	ctx := context.Background()
+	repo := sqlite.NewSQLiteProjectRepository(env.db, env.clock)
+
+	settings := usecase.NewSettingsManager(newTestProfile(t, ""))
+	editor := newTestEditor(env)
+	projects := usecase.NewProjectService(repo, env.tracker, settings, editor)
+
+	project, err := projects.CreateProject(ctx, "客戶 A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := projects.CreateTag(ctx, "計費")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := projects.CreateTag(ctx, "會議")
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := editor.Add(ctx, manualActivity(start.Add(-time.Hour), start))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagIDs := func() []int64 {
+		t.Helper()
+		tags, err := projects.ActivityTags(ctx, saved.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int64
+		for _, tag := range tags {
+			ids = append(ids, tag.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	if err := editor.SetTags(ctx, saved.ID, []int64{first.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.SetTags(ctx, saved.ID, []int64{first.ID, second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.SetCurrentProject(ctx, project.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := settings.GetSettings().CurrentProjectID; got != project.ID {
+		t.Fatalf("保存的目前專案 = %d，應為 %d", got, project.ID)
+	}
+
+	// 復原的順序與變更相反
+	if label, err := editor.Undo(ctx); err != nil || label != "切換目前的專案" {
+		t.Fatalf("Undo = %q, %v", label, err)
+	}
+	if env.tracker.CurrentProject() != 0 || settings.GetSettings().CurrentProjectID != 0 {
+		t.Errorf("復原後目前的專案 = %d，設定 = %d，應為 0",
+			env.tracker.CurrentProject(), settings.GetSettings().CurrentProjectID)
+	}
+	if label, err := editor.Undo(ctx); err != nil || label != "變更標籤" {
+		t.Fatalf("Undo = %q, %v", label, err)
+	}
+	if got := tagIDs(); !slices.Equal(got, []int64{first.ID}) {
+		t.Errorf("復原後的標籤 = %v，應為 [%d]", got, first.ID)
+	}
+
+	if _, err := editor.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editor.Redo(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := tagIDs(); !slices.Equal(got, []int64{first.ID, second.ID}) {
+		t.Errorf("重做後的標籤 = %v", got)
+	}
+	if env.tracker.CurrentProject() != project.ID || settings.GetSettings().CurrentProjectID != project.ID {
+		t.Errorf("重做後目前的專案 = %d，設定 = %d，應為 %d",
+			env.tracker.CurrentProject(), settings.GetSettings().CurrentProjectID, project.ID)
+	}
+
+	var undoSources int
+	for _, entry := range env.auditLog(t) {
+		if entry.Source == domain.AuditUndo {
+			undoSources++
+		}
+	}
+	if undoSources != 2 {
+		t.Errorf("標籤的復原與重做應各寫入一筆來源為 undo 的稽核記錄，實際 %d 筆", undoSources)
+	}
+}
@@ -2,165 +2,1027 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"log"
-	"sort"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 
 	"main/internal/domain"
 	"main/internal/repository"
+	"main/pkg/clock"
 )
 
+// heartbeatInterval 是寫入心跳的最短間隔
+const heartbeatInterval = 10 * time.Second
+
+// clockJumpThreshold 是牆上時鐘比單調時鐘多走超過多少時視為系統休眠
+const clockJumpThreshold = 30 * time.Second
+
+var (
+	ErrTrackerClosed  = errors.New("活動追蹤器已關閉")
+	ErrTrackerRunning = errors.New("活動追蹤器已在執行")
+)
+
+// ActivityTracker 的狀態由 mu 保護，輸入監聽、閾值檢查與 UI 可以同時呼叫。
+// 每種輸入類型各自記錄活動，滑鼠與鍵盤的活動可以同時進行。
 type ActivityTracker struct {
-	isActive         bool
-	lastActivity     time.Time
-	repo             repository.ActivityRepository
-	activities       []domain.Activity
-	thresholdSeconds int
+	mu                 sync.Mutex
+	tracks             map[domain.ActivityType]*activityTrack
+	lastActivity       time.Time // 任一類型最後一次輸入的時間
+	lastHeartbeatWrite time.Time
+	repo               repository.ActivityRepository
+	stats              *dailyStatsCache
+	openLookup         openStatsLookupCache
+	clock              clock.Clock
+	thresholdSeconds   int
+	idleSources        map[ThresholdIdleSource]bool // 監聽中、閾值跟著 thresholdSeconds 更新的閒置來源
+	typeThresholds     map[domain.ActivityType]int  // 各類型的閒置閾值，沒有設定時使用 thresholdSeconds
+	sessionGap         time.Duration
+	calendar           domain.Calendar
+	locked             bool      // 螢幕鎖定中，不記錄輸入
+	asleep             bool      // 系統休眠中，不記錄輸入
+	asleepSince        time.Time // 收到休眠事件的時間
+	lastTickWall       time.Time // 上次檢查時的牆上時間，用於偵測時鐘跳躍
+	lastTickMono       time.Duration
+	focus              domain.FocusPeriod // 目前的焦點時段，ID 為 0 表示沒有記錄中的時段
+	lastFocus          *domain.Focus      // 焦點來源最後回報的視窗，鎖定或休眠結束後據此恢復記錄
+	hideTitles         bool
+	rules              *RuleEngine // 依焦點將活動歸類到專案，nil 表示不歸類
+	currentProject     int64       // 沒有符合的規則時新活動所屬的專案，0 表示沒有專案
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stopped bool
 }
 
-func NewActivityTracker(repo repository.ActivityRepository) *ActivityTracker {
+// activityTrack 是單一輸入類型的活動狀態
+type activityTrack struct {
+	active        bool
+	lastActivity  time.Time
+	lastHeartbeat time.Time       // 最後寫入心跳的輸入時間
+	current       domain.Activity // 進行中的活動，只在 active 時有效
+}
+
+func NewActivityTracker(repo repository.ActivityRepository, statsRepo repository.StatsRepository, clk clock.Clock) *ActivityTracker {
 	return &ActivityTracker{
 		repo:             repo,
-		isActive:         false,
-		activities:       make([]domain.Activity, 0),
+		stats:            newDailyStatsCache(statsRepo),
+		clock:            clk,
+		tracks:           make(map[domain.ActivityType]*activityTrack),
 		thresholdSeconds: 15, // 預設值
+		typeThresholds:   make(map[domain.ActivityType]int),
+		idleSources:      make(map[ThresholdIdleSource]bool),
+		sessionGap:       time.Minute,
+		calendar:         domain.NewCalendar(time.Local, 0),
 	}
 }
 
 func (t *ActivityTracker) StartActivity(activityType domain.ActivityType) error {
-	if !t.isActive {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTrackerClosed
+	}
+	if t.locked || t.asleep {
+		// 鎖定畫面上的輸入（例如輸入密碼）不算工作時間
+		return nil
+	}
+
+	track, ok := t.tracks[activityType]
+	if !ok {
+		track = &activityTrack{}
+		t.tracks[activityType] = track
+	}
+
+	// 休眠後閾值檢查可能還沒執行，先結束已經閒置的活動，避免活動跨過休眠期間
+	if track.active && t.clock.Since(track.lastActivity) > t.thresholdLocked(activityType) {
+		if err := t.stopTrackLocked(track); err != nil {
+			return err
+		}
+	}
+
+	// 規則的時間條件改變時，進行中的活動在最後一次輸入結束，這次輸入以新的專案開始新的活動
+	now := t.clock.Now()
+	project := t.projectLocked(now)
+	if track.active && track.current.ProjectID != project {
+		if err := t.stopTrackLocked(track); err != nil {
+			return err
+		}
+	}
+
+	if !track.active {
 		activity := domain.Activity{
-			Type: activityType,
+			Type:      activityType,
+			ProjectID: project,
 		}
-		activity.SetStartTime(time.Now())
+		activity.SetStartTime(now)
 
 		log.Printf("開始新活動: 類型=%v, 開始時間=%v", activityType, activity.StartTime())
 
-		if err := t.repo.Save(context.Background(), activity); err != nil {
+		saved, err := t.repo.Save(context.Background(), activity)
+		if err != nil {
 			return err
 		}
-
-		t.activities = append(t.activities, activity)
-		t.isActive = true
+		track.current = saved
+		track.active = true
 	}
-	t.lastActivity = time.Now()
+	// 只保留牆上時間，讓 Since 把休眠期間也算進經過的時間
+	track.lastActivity = t.clock.Now().Round(0)
+	t.lastActivity = track.lastActivity
 	return nil
 }
 
+// SetCurrentProject 設定之後沒有符合規則的活動所屬的專案，0 表示沒有專案。
+// 因此改變專案的進行中活動在此結束，下一次輸入以新的專案開始新的活動。
+func (t *ActivityTracker) SetCurrentProject(projectID int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTrackerClosed
+	}
+	if t.currentProject == projectID {
+		return nil
+	}
+
+	log.Printf("切換目前的專案: %d -> %d", t.currentProject, projectID)
+	t.currentProject = projectID
+	return t.stopReclassifiedLocked(t.clock.Now())
+}
+
+// CurrentProject 回傳沒有符合規則時新活動所屬的專案，0 表示沒有專案
+func (t *ActivityTracker) CurrentProject() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.currentProject
+}
+
 func (t *ActivityTracker) StopActivity() error {
-	if t.isActive && len(t.activities) > 0 {
-		lastIdx := len(t.activities) - 1
-		t.activities[lastIdx].SetEndTime(t.lastActivity)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.stopActivityLocked()
+}
+
+// stopActivityLocked 結束所有類型進行中的活動
+func (t *ActivityTracker) stopActivityLocked() error {
+	var firstErr error
+	for _, activityType := range slices.Sorted(maps.Keys(t.tracks)) {
+		if err := t.stopTrackLocked(t.tracks[activityType]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (t *ActivityTracker) stopTrackLocked(track *activityTrack) error {
+	if track.active {
+		track.current.SetEndTime(track.lastActivity)
 
-		log.Printf("停止活動: 結束時間=%v", t.activities[lastIdx].EndTime())
+		log.Printf("停止活動: 類型=%v, 結束時間=%v", track.current.Type, track.current.EndTime())
 
-		if err := t.repo.UpdateEndTime(context.Background(), t.activities[lastIdx]); err != nil {
+		if err := t.repo.UpdateEndTime(context.Background(), track.current); err != nil {
 			return err
 		}
 
-		t.isActive = false
+		track.active = false
+		t.stats.InvalidateSince(track.current.StartTime())
 	}
 	return nil
 }
 
+// openActivitiesLocked 依類型順序回傳進行中的活動
+func (t *ActivityTracker) openActivitiesLocked() []domain.Activity {
+	var open []domain.Activity
+	for _, activityType := range slices.Sorted(maps.Keys(t.tracks)) {
+		if track := t.tracks[activityType]; track.active {
+			open = append(open, track.current)
+		}
+	}
+	return open
+}
+
+func (t *ActivityTracker) thresholdLocked(activityType domain.ActivityType) time.Duration {
+	seconds := t.thresholdSeconds
+	if typeSeconds := t.typeThresholds[activityType]; typeSeconds > 0 {
+		seconds = typeSeconds
+	}
+	return time.Second * time.Duration(seconds)
+}
+
+// GetDailyStats 回傳每日統計：已結束的活動由資料庫彙總並快取，再併入進行中的活動。
+// 總時間是各類型時間的聯集，同時有滑鼠與鍵盤輸入的時間只算一次。
 func (t *ActivityTracker) GetDailyStats() []domain.DailyStats {
-	statsMap := make(map[string]*domain.DailyStats)
+	t.mu.Lock()
+	cal := t.calendar
+	open := t.openActivitiesLocked()
+	focusID := t.focus.ID
+	now := t.clock.Now()
+	t.mu.Unlock()
 
-	for _, activity := range t.activities {
-		startTime := activity.StartTime()
-		// 跳過無效的時間
-		if startTime.Year() < 2000 {
-			continue
+	ctx := context.Background()
+	stats, err := t.stats.Get(ctx, cal)
+	if err != nil {
+		log.Printf("查詢每日統計時發生錯誤: %v", err)
+		return nil
+	}
+	if len(open) == 0 {
+		return stats
+	}
+
+	lookup, err := t.lookupOpen(ctx, open, focusID, now)
+	if err != nil {
+		log.Printf("查詢每日統計時發生錯誤: %v", err)
+		return nil
+	}
+
+	intervals := openIntervals(open, lookup.others, now)
+	for _, interval := range intervals {
+		switch {
+		case !interval.overlap:
+			stats = mergeDailyStats(stats, cal, interval.activityType, interval.start, interval.end)
+			stats = mergeProjectStats(stats, cal, interval.projectID, interval.start, interval.end, 1)
+		case interval.sameProject:
+			stats = subtractDailyOverlap(stats, cal, interval.start, interval.end)
+			stats = mergeProjectStats(stats, cal, interval.projectID, interval.start, interval.end, -1)
+		default:
+			stats = subtractDailyOverlap(stats, cal, interval.start, interval.end)
+		}
+	}
+	return mergeOpenFocus(stats, cal, intervals, lookup.focus, now)
+}
+
+// openStatsLookup 是併入進行中的活動時需要向資料庫查詢的資料
+type openStatsLookup struct {
+	others [][]domain.Activity  // 與每筆進行中的活動重疊、不同類型的已結束活動
+	focus  []domain.FocusPeriod // 進行中的活動開始後的焦點時段
+}
+
+// openStatsLookupCache 快取 openStatsLookup，UI 每秒查詢統計時不必每次都查詢資料庫。
+// 已結束的活動或焦點時段改變時每日統計會失效，進行中的活動或焦點時段改變時鍵值不同，都會重新查詢。
+type openStatsLookupCache struct {
+	mu      sync.Mutex
+	version uint64
+	openIDs []int64
+	focusID int64
+	lookup  openStatsLookup
+	valid   bool
+}
+
+// lookupOpen 回傳 open 的 openStatsLookup，快取仍然有效時不查詢資料庫
+func (t *ActivityTracker) lookupOpen(ctx context.Context, open []domain.Activity, focusID int64, now time.Time) (openStatsLookup, error) {
+	openIDs := make([]int64, len(open))
+	for i, activity := range open {
+		openIDs[i] = activity.ID
+	}
+	// 先取得版本再查詢，查詢中途失效時下次會因版本不同而重新查詢
+	version := t.stats.Version()
+
+	cache := &t.openLookup
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.valid && cache.version == version && cache.focusID == focusID && slices.Equal(cache.openIDs, openIDs) {
+		return cache.lookup, nil
+	}
+
+	var lookup openStatsLookup
+	from := now
+	for _, current := range open {
+		from = earlier(from, current.StartTime())
+
+		var others []domain.Activity
+		for other, err := range t.repo.GetActivitiesBetween(ctx, current.StartTime(), now) {
+			if err != nil {
+				return lookup, err
+			}
+			if other.Type != current.Type && other.IsEnded() {
+				others = append(others, other)
+			}
+		}
+		lookup.others = append(lookup.others, others)
+	}
+
+	periods, err := t.repo.GetFocusBetween(ctx, from, now)
+	if err != nil {
+		return lookup, err
+	}
+	lookup.focus = periods
+
+	cache.version, cache.openIDs, cache.focusID = version, openIDs, focusID
+	cache.lookup, cache.valid = lookup, true
+	return lookup, nil
+}
+
+// statsInterval 是進行中的活動要併入每日統計的一段時間，overlap 表示要從總時間扣除的重疊時間
+type statsInterval struct {
+	activityType domain.ActivityType
+	projectID    int64
+	start, end   time.Time
+	overlap      bool
+	sameProject  bool // 重疊的兩筆活動屬於同一個專案，也從專案時間扣除
+	manual       bool // 與手動新增或修改過的活動重疊，不分配到焦點時段
+}
+
+// openIntervals 回傳進行中的活動到 now 為止的時間，以及它們與其他類型活動重疊的時間，
+// others[i] 是與 open[i] 重疊、不同類型的已結束活動
+func openIntervals(open []domain.Activity, others [][]domain.Activity, now time.Time) []statsInterval {
+	var intervals []statsInterval
+	for i, current := range open {
+		start := current.StartTime()
+		intervals = append(intervals, statsInterval{
+			activityType: current.Type,
+			projectID:    current.ProjectID,
+			start:        start,
+			end:          now,
+		})
+
+		// 進行中的活動之間的重疊只扣除一次
+		for _, other := range open[i+1:] {
+			intervals = append(intervals, statsInterval{
+				projectID:   current.ProjectID,
+				start:       later(start, other.StartTime()),
+				end:         now,
+				overlap:     true,
+				sameProject: other.ProjectID == current.ProjectID,
+			})
 		}
 
-		date := startTime.Format("2006-01-02")
-		if _, exists := statsMap[date]; !exists {
-			statsMap[date] = &domain.DailyStats{
-				Date: startTime.Truncate(24 * time.Hour), // 確保日期是當天的開始時間
+		for _, other := range others[i] {
+			intervals = append(intervals, statsInterval{
+				projectID:   current.ProjectID,
+				start:       later(start, other.StartTime()),
+				end:         earlier(now, other.EndTime()),
+				overlap:     true,
+				sameProject: other.ProjectID == current.ProjectID,
+				manual:      other.Manual,
+			})
+		}
+	}
+	return intervals
+}
+
+// mergeOpenFocus 將進行中的活動時間依焦點時段分配到各應用程式
+func mergeOpenFocus(stats []domain.DailyStats, cal domain.Calendar, intervals []statsInterval, periods []domain.FocusPeriod, now time.Time) []domain.DailyStats {
+	for _, period := range periods {
+		end := period.End
+		if end.IsZero() {
+			end = now
+		}
+		for _, interval := range intervals {
+			if interval.manual {
+				continue
 			}
+			sign := time.Duration(1)
+			if interval.overlap {
+				sign = -1
+			}
+			stats = mergeAppStats(stats, cal, period.App,
+				later(interval.start, period.Start), earlier(interval.end, end), sign)
 		}
+	}
+	return stats
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
 
-		var duration time.Duration
-		if !activity.IsEnded() {
-			if t.isActive {
-				duration = time.Since(startTime)
-			} else {
-				duration = t.lastActivity.Sub(startTime)
+// Track 從輸入來源接收事件並記錄活動，直到來源結束或 ctx 結束
+func (t *ActivityTracker) Track(ctx context.Context, source InputSource) error {
+	events, err := source.Events(ctx)
+	if err != nil {
+		return err
+	}
+
+	for ev := range events {
+		if err := t.StartActivity(ev.Type); err != nil {
+			if errors.Is(err, ErrTrackerClosed) {
+				return err
 			}
-		} else {
-			duration = activity.EndTime().Sub(startTime)
+			log.Printf("開始活動時發生錯誤: %v", err)
 		}
+	}
+	return nil
+}
 
-		// 確保持續時間是有效的
-		if duration < 0 {
-			continue
+// Watch 從閒置來源接收系統事件並立即結束或恢復活動，直到來源結束或 ctx 結束。
+// 來源是 ThresholdIdleSource 時，監聽期間它的閾值與追蹤器的閾值相同。
+func (t *ActivityTracker) Watch(ctx context.Context, source IdleSource) error {
+	if source, ok := source.(ThresholdIdleSource); ok {
+		t.mu.Lock()
+		source.UpdateThreshold(time.Duration(t.thresholdSeconds) * time.Second)
+		t.idleSources[source] = true
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.idleSources, source)
+			t.mu.Unlock()
+		}()
+	}
+
+	events, err := source.Events(ctx)
+	if err != nil {
+		return err
+	}
+
+	for ev := range events {
+		if err := t.HandleSystemEvent(ev); err != nil {
+			if errors.Is(err, ErrTrackerClosed) {
+				return err
+			}
+			log.Printf("處理系統事件時發生錯誤: %v", err)
 		}
+	}
+	return nil
+}
 
-		stats := statsMap[date]
-		stats.TotalDuration += duration
-		if activity.Type == domain.MouseActivity {
-			stats.MouseDuration += duration
-		} else {
-			stats.KeyboardDuration += duration
+// HandleSystemEvent 在閒置、鎖定或休眠時結束所有進行中的活動，
+// 鎖定與休眠期間忽略輸入，解鎖與喚醒後恢復記錄
+func (t *ActivityTracker) HandleSystemEvent(ev domain.SystemEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTrackerClosed
+	}
+
+	log.Printf("系統事件: 類型=%v, 時間=%v", ev.Kind, ev.Time.Format(time.RFC3339))
+
+	switch ev.Kind {
+	case domain.SystemIdle:
+		return t.stopActivityLocked()
+	case domain.SystemLock:
+		t.locked = true
+		return errors.Join(t.stopActivityLocked(), t.endFocusLocked(ev.Time))
+	case domain.SystemSuspend:
+		t.asleep = true
+		t.asleepSince = ev.Time
+		return errors.Join(t.stopActivityLocked(), t.endFocusLocked(ev.Time))
+	case domain.SystemUnlock:
+		t.locked = false
+		return t.restoreFocusLocked(ev.Time)
+	case domain.SystemResume:
+		return errors.Join(t.resumeLocked(ev.Time), t.restoreFocusLocked(ev.Time))
+	}
+	return nil
+}
+
+// TrackFocus 從焦點來源接收焦點改變並記錄焦點時段，直到來源結束或 ctx 結束
+func (t *ActivityTracker) TrackFocus(ctx context.Context, source FocusSource) error {
+	events, err := source.Events(ctx)
+	if err != nil {
+		return err
+	}
+
+	for ev := range events {
+		if err := t.HandleFocus(ev); err != nil {
+			if errors.Is(err, ErrTrackerClosed) {
+				return err
+			}
+			log.Printf("記錄焦點時發生錯誤: %v", err)
 		}
 	}
+	return nil
+}
 
-	var result []domain.DailyStats
-	for _, stats := range statsMap {
-		result = append(result, *stats)
+// HandleFocus 結束上一個焦點時段並開始新的時段，鎖定或休眠期間只記住最後的焦點
+func (t *ActivityTracker) HandleFocus(ev domain.FocusEvent) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.stopped {
+		return ErrTrackerClosed
 	}
 
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Date.After(result[j].Date) // 改為降序排序，最新的日期在前
-	})
+	focus := ev.Focus
+	if t.hideTitles {
+		focus.Title = ""
+	}
+	t.lastFocus = &focus
 
-	return result
+	if t.locked || t.asleep {
+		return nil
+	}
+	if t.focus.ID != 0 && t.focus.Focus == focus {
+		return nil
+	}
+	if err := t.endFocusLocked(ev.Time); err != nil {
+		return err
+	}
+	if err := t.startFocusLocked(focus, ev.Time); err != nil {
+		return err
+	}
+	return t.stopReclassifiedLocked(ev.Time)
 }
 
-func (t *ActivityTracker) LoadActivities() error {
-	activities, err := t.repo.GetActivities(context.Background())
+func (t *ActivityTracker) startFocusLocked(focus domain.Focus, at time.Time) error {
+	period, err := t.repo.StartFocus(context.Background(), focus, at)
 	if err != nil {
 		return err
 	}
-	t.activities = activities
-	log.Printf("載入活動記錄: 數量=%d", len(activities))
+	t.focus = period
 	return nil
 }
 
-func (t *ActivityTracker) GetTodayActivities() ([]domain.Activity, error) {
-	return t.repo.GetTodayActivities(context.Background())
+func (t *ActivityTracker) endFocusLocked(at time.Time) error {
+	if t.focus.ID == 0 {
+		return nil
+	}
+	if err := t.repo.EndFocus(context.Background(), t.focus.ID, at); err != nil {
+		return err
+	}
+	t.focus = domain.FocusPeriod{}
+	return nil
 }
 
-func (t *ActivityTracker) StartThresholdChecker() {
-	ticker := time.NewTicker(time.Second)
-	go func() {
-		for range ticker.C {
-			if t.isActive && time.Since(t.lastActivity) > time.Second*time.Duration(t.thresholdSeconds) {
-				if err := t.StopActivity(); err != nil {
-					log.Printf("停止活動時發生錯誤: %v", err)
+// restoreFocusLocked 在解鎖或喚醒後以最後回報的焦點開始新的時段
+func (t *ActivityTracker) restoreFocusLocked(at time.Time) error {
+	if t.locked || t.asleep || t.lastFocus == nil || t.focus.ID != 0 {
+		return nil
+	}
+	return t.startFocusLocked(*t.lastFocus, at)
+}
+
+// projectLocked 回傳在 at 開始的活動所屬的專案：目前的焦點符合規則時為規則的專案，否則為目前的專案。
+// 時間條件以設定的時區判斷。
+func (t *ActivityTracker) projectLocked(at time.Time) int64 {
+	if t.rules != nil && t.focus.ID != 0 {
+		if project := t.rules.Classify(t.focus.Focus, t.calendar.In(at)); project.ID != 0 {
+			return project.ID
+		}
+	}
+	return t.currentProject
+}
+
+// stopReclassifiedLocked 結束專案與 at 時應屬的專案不同的進行中活動，活動在最後一次輸入結束，
+// 下一次輸入以新的專案開始新的活動，讓每筆活動只屬於一個專案
+func (t *ActivityTracker) stopReclassifiedLocked(at time.Time) error {
+	project := t.projectLocked(at)
+	var firstErr error
+	for _, activityType := range slices.Sorted(maps.Keys(t.tracks)) {
+		track := t.tracks[activityType]
+		if track.active && track.current.ProjectID != project {
+			if err := t.stopTrackLocked(track); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// UpdateRules 設定歸類專案的規則，進行中的活動在下一次檢查時依新的規則結束；要套用到過去的記錄需呼叫 ApplyRules
+func (t *ActivityTracker) UpdateRules(rules *RuleEngine) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rules = rules
+}
+
+// ApplyRules 以目前的規則重新歸類追蹤器記錄、已結束的活動：活動在焦點改變與規則時間條件改變的時間分段，
+// 各段依當時取得焦點的視窗歸類，專案不同的相鄰段分割成不同的活動。
+// 沒有符合規則的部分，或手動新增、修改過的活動保留原本的專案。
+// 每筆變更都寫入來源為 rules 的稽核記錄，回傳重新歸類的活動數量。
+func (t *ActivityTracker) ApplyRules(ctx context.Context) (int, error) {
+	t.mu.Lock()
+	rules, cal := t.rules, t.calendar
+	now := t.clock.Now()
+	t.mu.Unlock()
+
+	if rules == nil {
+		return 0, nil
+	}
+
+	type reclassify struct {
+		id       int64
+		segments []domain.ProjectSegment
+	}
+	var changed []reclassify
+	timeline := focusTimeline{repo: t.repo}
+	for activity, err := range t.repo.GetActivitiesBetween(ctx, time.Unix(0, 0), now) {
+		if err != nil {
+			return 0, err
+		}
+		if activity.Manual || !activity.IsEnded() || !activity.EndTime().After(activity.StartTime()) {
+			continue
+		}
+
+		segments, err := ruleSegments(ctx, rules, cal, &timeline, activity)
+		if err != nil {
+			return 0, err
+		}
+		if len(segments) > 1 || segments[0].ProjectID != activity.ProjectID {
+			changed = append(changed, reclassify{activity.ID, segments})
+		}
+	}
+
+	// 先讀完所有活動再更新，每筆變更各自寫入稽核記錄
+	for i, c := range changed {
+		if _, err := t.repo.ReclassifyActivity(ctx, c.id, c.segments, domain.AuditRules); err != nil {
+			t.InvalidateStats()
+			return i, err
+		}
+	}
+	t.InvalidateStats()
+	return len(changed), nil
+}
+
+// ruleSegments 依焦點時段與規則的時間條件將 activity 分段歸類，相鄰且專案相同的段落合併。
+// 沒有焦點記錄或沒有符合規則的部分沿用活動原本的專案。
+func ruleSegments(ctx context.Context, rules *RuleEngine, cal domain.Calendar, timeline *focusTimeline, activity domain.Activity) ([]domain.ProjectSegment, error) {
+	start, end := activity.StartTime(), activity.EndTime()
+	periods, err := timeline.between(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []domain.ProjectSegment
+	for at := start; at.Before(end); {
+		project, next := activity.ProjectID, end
+		for _, period := range periods {
+			if period.Start.After(at) {
+				next = earlier(next, period.Start)
+				break
+			}
+			if period.End.IsZero() || period.End.After(at) {
+				if classified := rules.Classify(period.Focus, cal.In(at)); classified.ID != 0 {
+					project = classified.ID
+				}
+				if !period.End.IsZero() {
+					next = earlier(next, period.End)
+				}
+				if change := rules.NextChange(cal.In(at)); !change.IsZero() {
+					next = earlier(next, change)
 				}
 			}
 		}
-	}()
+
+		if len(segments) == 0 || segments[len(segments)-1].ProjectID != project {
+			segments = append(segments, domain.ProjectSegment{Start: at, ProjectID: project})
+		}
+		at = next
+	}
+	return segments, nil
+}
+
+// focusTimeline 依時間順序查詢焦點時段，每次向資料庫讀取至少一天的時段
+type focusTimeline struct {
+	repo     repository.ActivityRepository
+	from, to time.Time
+	periods  []domain.FocusPeriod
+}
+
+// between 依開始時間順序回傳與 [start, end) 重疊的焦點時段
+func (f *focusTimeline) between(ctx context.Context, start, end time.Time) ([]domain.FocusPeriod, error) {
+	if f.periods == nil || start.Before(f.from) || end.After(f.to) {
+		f.from, f.to = start, later(end, start.Add(24*time.Hour))
+		periods, err := f.repo.GetFocusBetween(ctx, f.from, f.to)
+		if err != nil {
+			return nil, err
+		}
+		f.periods = append([]domain.FocusPeriod{}, periods...)
+	}
+
+	var result []domain.FocusPeriod
+	for _, period := range f.periods {
+		if period.Start.Before(end) && (period.End.IsZero() || period.End.After(start)) {
+			result = append(result, period)
+		}
+	}
+	return result, nil
+}
+
+// UpdateHideWindowTitles 設定是否只記錄應用程式而不記錄視窗標題，從下一次焦點改變開始生效
+func (t *ActivityTracker) UpdateHideWindowTitles(hide bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.hideTitles = hide
+}
+
+// resumeLocked 記錄休眠時段並恢復記錄輸入
+func (t *ActivityTracker) resumeLocked(at time.Time) error {
+	wasAsleep, since := t.asleep, t.asleepSince
+	t.asleep = false
+	t.asleepSince = time.Time{}
+
+	// 休眠時段已由喚醒事件記錄，重設時鐘跳躍的基準，避免重複記錄
+	t.lastTickWall, t.lastTickMono = t.clock.Now(), t.clock.Monotonic()
+
+	if !wasAsleep || since.IsZero() || !at.After(since) {
+		return nil
+	}
+	return t.saveGapLocked(domain.Gap{Start: since, End: at, Reason: domain.GapSuspend})
+}
+
+// detectClockJumpLocked 比較牆上時鐘與單調時鐘，兩者相差過多表示系統曾經休眠。
+// 休眠前的活動以最後一次輸入的時間結束，沒有收到休眠事件時另外記錄休眠時段。
+func (t *ActivityTracker) detectClockJumpLocked() {
+	now, mono := t.clock.Now(), t.clock.Monotonic()
+	lastWall, lastMono := t.lastTickWall, t.lastTickMono
+	t.lastTickWall, t.lastTickMono = now, mono
+
+	if lastWall.IsZero() {
+		return
+	}
+	jump := now.Round(0).Sub(lastWall.Round(0)) - (mono - lastMono)
+	if jump < clockJumpThreshold {
+		return
+	}
+
+	log.Printf("偵測到時鐘跳躍: %v，視為系統休眠", jump)
+	if err := t.stopActivityLocked(); err != nil {
+		log.Printf("停止活動時發生錯誤: %v", err)
+	}
+	if t.asleep {
+		// 收到休眠事件時由喚醒事件記錄休眠時段
+		return
+	}
+
+	start := lastWall.Round(0)
+	gap := domain.Gap{Start: start, End: start.Add(jump), Reason: domain.GapClockJump}
+	if err := t.saveGapLocked(gap); err != nil {
+		log.Printf("記錄休眠時段時發生錯誤: %v", err)
+	}
+}
+
+func (t *ActivityTracker) saveGapLocked(gap domain.Gap) error {
+	log.Printf("記錄休眠時段: 開始=%v, 結束=%v, 原因=%v",
+		gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339), gap.Reason)
+
+	_, err := t.repo.SaveGap(context.Background(), gap)
+	return err
+}
+
+// GetTodayGaps 回傳今天的休眠時段
+func (t *ActivityTracker) GetTodayGaps() ([]domain.Gap, error) {
+	start, end := t.Today()
+	return t.repo.GetGapsBetween(context.Background(), start, end)
+}
+
+// GetTodayFocus 回傳今天的焦點時段，仍在使用中的時段算到目前為止
+func (t *ActivityTracker) GetTodayFocus() ([]domain.FocusPeriod, error) {
+	start, end := t.Today()
+	periods, err := t.repo.GetFocusBetween(context.Background(), start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.clock.Now()
+	for i := range periods {
+		if periods[i].End.IsZero() {
+			periods[i].End = now
+		}
+	}
+	return periods, nil
+}
+
+func (t *ActivityTracker) GetTodayActivities() ([]domain.Activity, error) {
+	return t.repo.GetTodayActivities(context.Background(), t.Calendar())
+}
+
+// GetTodaySessions 將今天的活動依設定的間隔合併成工作階段，進行中的活動算到目前為止
+func (t *ActivityTracker) GetTodaySessions() ([]domain.Session, error) {
+	activities, err := t.GetTodayActivities()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	gap := t.sessionGap
+	openEnd := t.lastActivity
+	if t.isActiveLocked() {
+		openEnd = t.clock.Now()
+	}
+	t.mu.Unlock()
+
+	return BuildSessions(activities, gap, openEnd), nil
+}
+
+// Run 每秒檢查閾值並寫入心跳，直到 ctx 結束或呼叫 Close
+func (t *ActivityTracker) Run(ctx context.Context) error {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return ErrTrackerClosed
+	}
+	if t.done != nil {
+		t.mu.Unlock()
+		return ErrTrackerRunning
+	}
+	ctx, t.cancel = context.WithCancel(ctx)
+	t.done = make(chan struct{})
+	done := t.done
+	t.mu.Unlock()
+
+	defer close(done)
+
+	ticker := t.clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			t.tick()
+		}
+	}
+}
+
+// Close 停止 Run 並結束進行中的活動與焦點時段，之後不再接受新的活動
+func (t *ActivityTracker) Close() error {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return nil
+	}
+	t.stopped = true
+	cancel, done := t.cancel, t.done
+	t.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return errors.Join(t.stopActivityLocked(), t.endFocusLocked(t.clock.Now()))
+}
+
+func (t *ActivityTracker) tick() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.detectClockJumpLocked()
+
+	for _, activityType := range slices.Sorted(maps.Keys(t.tracks)) {
+		track := t.tracks[activityType]
+		if track.active && t.clock.Since(track.lastActivity) > t.thresholdLocked(activityType) {
+			if err := t.stopTrackLocked(track); err != nil {
+				log.Printf("停止活動時發生錯誤: %v", err)
+			}
+		}
+	}
+	// 規則的時間條件改變而沒有新的輸入時，讓進行中的活動停在改變之前
+	if err := t.stopReclassifiedLocked(t.clock.Now()); err != nil {
+		log.Printf("停止活動時發生錯誤: %v", err)
+	}
+	t.writeHeartbeatLocked()
+}
+
+// writeHeartbeatLocked 定期保存各類型最後活動時間，供異常結束後復原未完成的活動
+func (t *ActivityTracker) writeHeartbeatLocked() {
+	if t.clock.Since(t.lastHeartbeatWrite) < heartbeatInterval {
+		return
+	}
+
+	lastSeen := make(map[domain.ActivityType]int64)
+	for activityType, track := range t.tracks {
+		if track.active && track.lastActivity.After(track.lastHeartbeat) {
+			lastSeen[activityType] = track.lastActivity.Unix()
+		}
+	}
+	if len(lastSeen) == 0 {
+		return
+	}
+
+	if err := t.repo.SaveHeartbeat(context.Background(), lastSeen); err != nil {
+		log.Printf("寫入心跳時發生錯誤: %v", err)
+		return
+	}
+	for activityType := range lastSeen {
+		track := t.tracks[activityType]
+		track.lastHeartbeat = track.lastActivity
+	}
+	t.lastHeartbeatWrite = t.clock.Now()
 }
 
 func (t *ActivityTracker) UpdateThreshold(seconds int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	t.thresholdSeconds = seconds
+	for source := range t.idleSources {
+		source.UpdateThreshold(time.Duration(seconds) * time.Second)
+	}
+}
+
+// UpdateTypeThreshold 設定單一輸入類型的閒置閾值，0 表示使用 UpdateThreshold 的設定
+func (t *ActivityTracker) UpdateTypeThreshold(activityType domain.ActivityType, seconds int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.typeThresholds[activityType] = seconds
+}
+
+// UpdateSessionGap 設定合併工作階段時允許的最大間隔
+func (t *ActivityTracker) UpdateSessionGap(seconds int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sessionGap = time.Second * time.Duration(seconds)
+}
+
+// InvalidateStats 讓每日統計在下次查詢時重新向資料庫彙總
+func (t *ActivityTracker) InvalidateStats() {
+	t.stats.Invalidate()
+}
+
+// UpdateCalendar 更新每日邊界，每日彙總會依新的邊界重新建立
+func (t *ActivityTracker) UpdateCalendar(cal domain.Calendar) error {
+	if err := t.repo.UseCalendar(context.Background(), cal); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.calendar = cal
+	t.mu.Unlock()
+
+	t.stats.Invalidate()
+	return nil
 }
 
+func (t *ActivityTracker) Calendar() domain.Calendar {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.calendar
+}
+
+// Today 回傳目前這一天的 [start, end) 範圍
+func (t *ActivityTracker) Today() (time.Time, time.Time) {
+	return t.Calendar().DayRange(t.clock.Now())
+}
+
+// IsActive 回傳是否有任一類型的活動正在進行
 func (t *ActivityTracker) IsActive() bool {
-	return t.isActive
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.isActiveLocked()
+}
+
+func (t *ActivityTracker) isActiveLocked() bool {
+	for _, track := range t.tracks {
+		if track.active {
+			return true
+		}
+	}
+	return false
 }
 
 func (t *ActivityTracker) GetLastActivityTime() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	return t.lastActivity
 }
 
-func (t *ActivityTracker) CleanupUnfinishedActivities() error {
-	if err := t.repo.CleanupUnfinishedActivities(context.Background()); err != nil {
+// RecoverUnfinishedActivities 為上次異常結束時未關閉的活動補上結束時間
+func (t *ActivityTracker) RecoverUnfinishedActivities() error {
+	t.mu.Lock()
+	thresholdSeconds := t.thresholdSeconds
+	t.mu.Unlock()
+
+	report, err := t.repo.RecoverUnfinishedActivities(context.Background(), thresholdSeconds)
+	if err != nil {
 		return err
 	}
-	return t.LoadActivities() // 重新載入活動列表
+
+	heartbeatStr := "無"
+	if !report.Heartbeat.IsZero() {
+		heartbeatStr = report.Heartbeat.Format(time.RFC3339)
+	}
+	log.Printf("復原未完成活動: 數量=%d, 焦點時段=%d, 最後心跳=%s", len(report.Recovered), report.Focus, heartbeatStr)
+	for _, recovered := range report.Recovered {
+		log.Printf("復原活動: ID=%d, 類型=%v, 開始=%v, 結束=%v, 來源=%v",
+			recovered.Activity.ID,
+			recovered.Activity.Type,
+			recovered.Activity.StartTime().Format(time.RFC3339),
+			recovered.Activity.EndTime().Format(time.RFC3339),
+			recovered.Source)
+	}
+
+	t.stats.Invalidate()
+	return nil
 }
 
 // ... 實現其他方法 ...
@@ -0,0 +1,353 @@
+package usecase_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"main/internal/domain"
+	"main/internal/repository/sqlite"
+	"main/internal/repository/sqlite/migrations"
+	"main/internal/usecase"
+	"main/pkg/clock"
+)
+
+func TestMain(m *testing.M) {
+	// 追蹤器每次輸入都會寫記錄檔，測試時不輸出
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testEnv 是使用記憶體資料庫與假時鐘的追蹤器
+type testEnv struct {
+	db      *sql.DB
+	repo    *sqlite.SQLiteActivityRepository
+	clock   *clock.Fake
+	tracker *usecase.ActivityTracker
+}
+
+func newTestEnv(t *testing.T, start time.Time) *testEnv {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 記憶體資料庫每個連線各自獨立，只能使用一個連線
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := migrations.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	clk := clock.NewFake(start)
+	repo := sqlite.NewSQLiteActivityRepository(db, clk)
+	tracker := usecase.NewActivityTracker(repo, sqlite.NewSQLiteStatsRepository(db), clk)
+	if err := tracker.UpdateCalendar(domain.NewCalendar(time.UTC, 0)); err != nil {
+		t.Fatal(err)
+	}
+	return &testEnv{db: db, repo: repo, clock: clk, tracker: tracker}
+}
+
+// waitFor 等待背景的 goroutine 讓 cond 成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待逾時: %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestActivityTrackerConcurrentInputAndReads(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	tracker := env.tracker
+	tracker.UpdateThreshold(5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	var wg sync.WaitGroup
+	spawn := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	for _, activityType := range []domain.ActivityType{domain.MouseActivity, domain.KeyboardActivity} {
+		spawn(func() {
+			for range 200 {
+				if err := tracker.StartActivity(activityType); err != nil {
+					t.Errorf("StartActivity(%v): %v", activityType, err)
+					return
+				}
+			}
+		})
+	}
+	spawn(func() {
+		for range 50 {
+			for _, kind := range []domain.SystemEventKind{domain.SystemLock, domain.SystemUnlock, domain.SystemIdle} {
+				if err := tracker.HandleSystemEvent(domain.SystemEvent{Kind: kind, Time: env.clock.Now()}); err != nil {
+					t.Errorf("HandleSystemEvent(%v): %v", kind, err)
+					return
+				}
+			}
+		}
+	})
+	spawn(func() {
+		for i := range 100 {
+			app := []string{"editor", "browser", "terminal"}[i%3]
+			if err := tracker.HandleFocus(domain.FocusEvent{Focus: domain.Focus{App: app}, Time: env.clock.Now()}); err != nil {
+				t.Errorf("HandleFocus: %v", err)
+				return
+			}
+		}
+	})
+	spawn(func() {
+		for range 100 {
+			env.clock.Advance(time.Second)
+		}
+	})
+	for range 2 {
+		spawn(func() {
+			for range 100 {
+				tracker.GetDailyStats()
+				if _, err := tracker.GetTodayActivities(); err != nil {
+					t.Errorf("GetTodayActivities: %v", err)
+					return
+				}
+				if _, err := tracker.GetTodaySessions(); err != nil {
+					t.Errorf("GetTodaySessions: %v", err)
+					return
+				}
+				tracker.IsActive()
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := tracker.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	activities, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) == 0 {
+		t.Fatal("沒有記錄任何活動")
+	}
+	for _, activity := range activities {
+		if !activity.IsEnded() {
+			t.Errorf("關閉後仍有進行中的活動: %+v", activity)
+		}
+	}
+}
+
+func TestActivityTrackerRunClose(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+	tracker := env.tracker
+	tracker.UpdateThreshold(10)
+
+	// 同時呼叫兩次 Run，只有一個能執行，另一個立即回傳 ErrTrackerRunning
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 2)
+	for range 2 {
+		go func() { runErr <- tracker.Run(ctx) }()
+	}
+	if err := <-runErr; !errors.Is(err, usecase.ErrTrackerRunning) {
+		t.Fatalf("第二次 Run = %v，應為 ErrTrackerRunning", err)
+	}
+
+	// 超過閾值沒有輸入時，由 Run 的定期檢查以最後一次輸入的時間結束活動
+	if err := tracker.StartActivity(domain.MouseActivity); err != nil {
+		t.Fatal(err)
+	}
+	env.clock.Advance(3 * time.Second)
+	if err := tracker.StartActivity(domain.MouseActivity); err != nil {
+		t.Fatal(err)
+	}
+	env.clock.Advance(15 * time.Second)
+	waitFor(t, "閾值到期後結束活動", func() bool {
+		env.clock.Advance(time.Second)
+		return !tracker.IsActive()
+	})
+
+	activities, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 || !activities[0].EndTime().Equal(start.Add(3*time.Second)) {
+		t.Fatalf("閾值到期後的活動 = %+v，應在 09:00:03 結束", activities)
+	}
+
+	// Close 結束 Run 與進行中的活動，之後不再接受輸入
+	if err := tracker.StartActivity(domain.KeyboardActivity); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("Close 後 Run = %v", err)
+	}
+	if tracker.IsActive() {
+		t.Error("Close 後仍有進行中的活動")
+	}
+	if err := tracker.StartActivity(domain.MouseActivity); !errors.Is(err, usecase.ErrTrackerClosed) {
+		t.Errorf("Close 後 StartActivity = %v，應為 ErrTrackerClosed", err)
+	}
+	if err := tracker.Run(context.Background()); !errors.Is(err, usecase.ErrTrackerClosed) {
+		t.Errorf("Close 後 Run = %v，應為 ErrTrackerClosed", err)
+	}
+	if err := tracker.Close(); err != nil {
+		t.Errorf("第二次 Close = %v", err)
+	}
+}
+
+func TestActivityTrackerRunStopsOnContextCancel(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- env.tracker.Run(ctx) }()
+
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+	if err := env.tracker.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+// thresholdIdleSource 記錄收到的閒置閾值，直到 ctx 結束都不送出事件
+type thresholdIdleSource struct {
+	mu        sync.Mutex
+	threshold time.Duration
+}
+
+func (s *thresholdIdleSource) UpdateThreshold(threshold time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.threshold = threshold
+}
+
+func (s *thresholdIdleSource) Threshold() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.threshold
+}
+
+func (s *thresholdIdleSource) Events(ctx context.Context) (<-chan domain.SystemEvent, error) {
+	events := make(chan domain.SystemEvent)
+	go func() {
+		<-ctx.Done()
+		close(events)
+	}()
+	return events, nil
+}
+
+func TestActivityTrackerUpdatesIdleSourceThreshold(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	tracker := env.tracker
+	tracker.UpdateThreshold(15)
+
+	source := &thresholdIdleSource{}
+	ctx, cancel := context.WithCancel(context.Background())
+	watchErr := make(chan error, 1)
+	go func() { watchErr <- tracker.Watch(ctx, source) }()
+
+	waitFor(t, "監聽時套用目前的閾值", func() bool { return source.Threshold() == 15*time.Second })
+
+	// 儲存設定時只會更新追蹤器，監聽中的閒置來源也要跟著更新
+	tracker.UpdateThreshold(120)
+	if got := source.Threshold(); got != 120*time.Second {
+		t.Errorf("更新閾值後閒置來源的閾值 = %v，應為 2m0s", got)
+	}
+
+	cancel()
+	if err := <-watchErr; err != nil {
+		t.Fatalf("Watch = %v", err)
+	}
+	tracker.UpdateThreshold(30)
+	if got := source.Threshold(); got != 120*time.Second {
+		t.Errorf("停止監聽後閒置來源的閾值 = %v，應維持 2m0s", got)
+	}
+}
+
+// TestActivityTrackerRecoversEachTypeAtItsOwnHeartbeat 確認追蹤器為每種類型寫入心跳，
+// 異常結束後先停止輸入的類型不會延長到其他類型最後的輸入
+func TestActivityTrackerRecoversEachTypeAtItsOwnHeartbeat(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+	env.tracker.UpdateThreshold(3600)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- env.tracker.Run(ctx) }()
+
+	env.work(t, start, start.Add(10*time.Minute), 10*time.Second, domain.MouseActivity, domain.KeyboardActivity)
+	env.work(t, start.Add(10*time.Minute+10*time.Second), start.Add(20*time.Minute), 10*time.Second, domain.MouseActivity)
+	waitFor(t, "寫入滑鼠最後的心跳", func() bool {
+		env.clock.Advance(time.Second)
+		var lastSeen int64
+		err := env.db.QueryRow(`SELECT last_seen FROM activity_heartbeats WHERE activity_type = ?`, domain.MouseActivity).Scan(&lastSeen)
+		return err == nil && lastSeen == start.Add(20*time.Minute).Unix()
+	})
+
+	// 不呼叫 Close 就停止，如同程式異常結束
+	cancel()
+	if err := <-runErr; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	tracker := usecase.NewActivityTracker(env.repo, sqlite.NewSQLiteStatsRepository(env.db), env.clock)
+	if err := tracker.UpdateCalendar(domain.NewCalendar(time.UTC, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.RecoverUnfinishedActivities(); err != nil {
+		t.Fatal(err)
+	}
+	activities, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[domain.ActivityType]time.Time{
+		domain.MouseActivity:    start.Add(20 * time.Minute),
+		domain.KeyboardActivity: start.Add(10 * time.Minute),
+	}
+	if len(activities) != len(want) {
+		t.Fatalf("復原後的活動 = %+v，應各有一筆滑鼠與鍵盤活動", activities)
+	}
+	for _, activity := range activities {
+		if !activity.StartTime().Equal(start) || !activity.EndTime().Equal(want[activity.Type]) {
+			t.Errorf("復原的 %v 活動 = %v - %v，應為 %v - %v",
+				activity.Type, activity.StartTime(), activity.EndTime(), start, want[activity.Type])
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+// AuditService 查詢與匯出活動變更的稽核記錄
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Entries 依時間順序回傳在 [from, to) 之間寫入的稽核記錄
+func (s *AuditService) Entries(ctx context.Context, from, to time.Time) ([]domain.AuditEntry, error) {
+	return s.repo.GetAuditLog(ctx, from, to)
+}
+
+// ExportCSV 將 [from, to) 之間的稽核記錄以 CSV 寫入 w，時間以 loc 表示
+func (s *AuditService) ExportCSV(ctx context.Context, w io.Writer, from, to time.Time, loc *time.Location) error {
+	entries, err := s.Entries(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"時間", "來源", "操作", "活動 ID", "變更前", "變更後", "說明"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		activityID := ""
+		if entry.ActivityID != 0 {
+			activityID = strconv.FormatInt(entry.ActivityID, 10)
+		}
+		if err := writer.Write([]string{
+			entry.Time.In(loc).Format(time.RFC3339),
+			string(entry.Source),
+			string(entry.Operation),
+			activityID,
+			FormatAuditActivity(entry.Before, loc),
+			FormatAuditActivity(entry.After, loc),
+			entry.Detail,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// FormatAuditActivity 以一行文字表示稽核記錄中的活動，nil 時回傳空字串
+func FormatAuditActivity(activity *domain.Activity, loc *time.Location) string {
+	if activity == nil {
+		return ""
+	}
+
+	end := "進行中"
+	if activity.IsEnded() {
+		end = activity.EndTime().In(loc).Format(time.DateTime)
+	}
+	text := fmt.Sprintf("%s %s - %s", activity.Type, activity.StartTime().In(loc).Format(time.DateTime), end)
+	if activity.ProjectID != 0 {
+		text += fmt.Sprintf(" 專案=%d", activity.ProjectID)
+	}
+	if activity.Manual {
+		text += " 手動"
+	}
+	return text
+}
@@ -0,0 +1,202 @@
+package usecase
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+// dailyStatsCache 快取已結束活動的每日統計，活動結束或每日邊界改變時重新向資料庫彙總。
+// 只有某一天之後改變時只重新彙總這些日期，較早的日期沿用快取。
+type dailyStatsCache struct {
+	mu      sync.Mutex
+	repo    repository.StatsRepository
+	stats   []domain.DailyStats
+	cal     domain.Calendar
+	valid   bool
+	since   time.Time // 非零值表示這個時間所在的那一天之後的統計已失效
+	version uint64
+}
+
+func newDailyStatsCache(repo repository.StatsRepository) *dailyStatsCache {
+	return &dailyStatsCache{repo: repo}
+}
+
+func (c *dailyStatsCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.valid = false
+	c.since = time.Time{}
+	c.version++
+}
+
+// InvalidateSince 讓 since 所在的那一天之後的統計失效，下次查詢時只重新彙總這些日期
+func (c *dailyStatsCache) InvalidateSince(since time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.since.IsZero() || since.Before(c.since) {
+		c.since = since
+	}
+	c.version++
+}
+
+// Version 回傳失效的次數，已結束的活動有任何改變時都會增加
+func (c *dailyStatsCache) Version() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+func (c *dailyStatsCache) Get(ctx context.Context, cal domain.Calendar) ([]domain.DailyStats, error) {
+	c.mu.Lock()
+	if c.valid && c.cal == cal && c.since.IsZero() {
+		stats := append([]domain.DailyStats(nil), c.stats...)
+		c.mu.Unlock()
+		return stats, nil
+	}
+	version := c.version
+	partial := c.valid && c.cal == cal
+	since := c.since
+	cached := c.stats
+	c.mu.Unlock()
+
+	// 查詢期間不持有鎖，查詢中途失效的結果不寫入快取
+	var stats []domain.DailyStats
+	var err error
+	if partial {
+		stats, err = c.repo.GetDailyStatsSince(ctx, cal, since)
+		if err == nil {
+			// 兩邊都依日期降序排列，重新彙總的日期都不早於 since 所在的那一天
+			day := cal.DayStart(since)
+			for _, stat := range cached {
+				if stat.Date.Before(day) {
+					stats = append(stats, stat)
+				}
+			}
+		}
+	} else {
+		stats, err = c.repo.GetDailyStats(ctx, cal)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.version == version {
+		c.stats = stats
+		c.cal = cal
+		c.valid = true
+		c.since = time.Time{}
+	}
+	c.mu.Unlock()
+
+	return append([]domain.DailyStats(nil), stats...), nil
+}
+
+// mergeDailyStats 將一段活動依 cal 切分後加入每日統計，stats 需依日期降序排列
+func mergeDailyStats(stats []domain.DailyStats, cal domain.Calendar, activityType domain.ActivityType, start, end time.Time) []domain.DailyStats {
+	for _, slice := range cal.Split(start, end) {
+		var idx int
+		stats, idx = dailyStatsIndex(stats, slice.DayStart)
+
+		duration := slice.Duration()
+		stats[idx].TotalDuration += duration
+		switch activityType {
+		case domain.MouseActivity:
+			stats[idx].MouseDuration += duration
+		case domain.ManualActivity:
+			stats[idx].ManualDuration += duration
+		default:
+			stats[idx].KeyboardDuration += duration
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Date.After(stats[j].Date) // 降序排序，最新的日期在前
+	})
+	return stats
+}
+
+// subtractDailyOverlap 從每日總時間扣除 [start, end) 這段不同類型重疊的時間，stats 需依日期降序排列
+func subtractDailyOverlap(stats []domain.DailyStats, cal domain.Calendar, start, end time.Time) []domain.DailyStats {
+	for _, slice := range cal.Split(start, end) {
+		var idx int
+		stats, idx = dailyStatsIndex(stats, slice.DayStart)
+		stats[idx].TotalDuration -= slice.Duration()
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Date.After(stats[j].Date) // 降序排序，最新的日期在前
+	})
+	return stats
+}
+
+// dailyStatsIndex 回傳 day 在 stats 中的位置，不存在時加入一筆空的統計
+func dailyStatsIndex(stats []domain.DailyStats, day time.Time) ([]domain.DailyStats, int) {
+	for i := range stats {
+		if stats[i].Date.Equal(day) {
+			return stats, i
+		}
+	}
+	return append(stats, domain.DailyStats{Date: day}), len(stats)
+}
+
+// mergeAppStats 將 [start, end) 乘上 sign 後加入應用程式 app 的每日時間。
+// Apps 可能與快取共用，修改前先複製。
+func mergeAppStats(stats []domain.DailyStats, cal domain.Calendar, app string, start, end time.Time, sign time.Duration) []domain.DailyStats {
+	for _, slice := range cal.Split(start, end) {
+		var idx int
+		stats, idx = dailyStatsIndex(stats, slice.DayStart)
+
+		apps := slices.Clone(stats[idx].Apps)
+		pos := slices.IndexFunc(apps, func(a domain.AppStats) bool { return a.App == app })
+		if pos < 0 {
+			apps = append(apps, domain.AppStats{App: app})
+			pos = len(apps) - 1
+		}
+		apps[pos].Duration += sign * slice.Duration()
+		sort.SliceStable(apps, func(i, j int) bool {
+			return apps[i].Duration > apps[j].Duration
+		})
+		stats[idx].Apps = apps
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Date.After(stats[j].Date) // 降序排序，最新的日期在前
+	})
+	return stats
+}
+
+// mergeProjectStats 將 [start, end) 乘上 sign 後加入專案的每日時間，projectID 為 0 表示沒有專案。
+// Projects 可能與快取共用，修改前先複製。
+func mergeProjectStats(stats []domain.DailyStats, cal domain.Calendar, projectID int64, start, end time.Time, sign time.Duration) []domain.DailyStats {
+	for _, slice := range cal.Split(start, end) {
+		var idx int
+		stats, idx = dailyStatsIndex(stats, slice.DayStart)
+
+		projects := slices.Clone(stats[idx].Projects)
+		pos := slices.IndexFunc(projects, func(p domain.ProjectStats) bool { return p.ProjectID == projectID })
+		if pos < 0 {
+			projects = append(projects, domain.ProjectStats{ProjectID: projectID})
+			pos = len(projects) - 1
+		}
+		projects[pos].Duration += sign * slice.Duration()
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].Duration > projects[j].Duration
+		})
+		stats[idx].Projects = projects
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Date.After(stats[j].Date) // 降序排序，最新的日期在前
+	})
+	return stats
+}
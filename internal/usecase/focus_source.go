@@ -0,0 +1,13 @@
+package usecase
+
+import (
+	"context"
+
+	"main/internal/domain"
+)
+
+// FocusSource 在取得焦點的應用程式或視窗標題改變時回報，
+// 回傳的 channel 在來源結束或 ctx 結束時關閉
+type FocusSource interface {
+	Events(ctx context.Context) (<-chan domain.FocusEvent, error)
+}
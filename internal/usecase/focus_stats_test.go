@@ -0,0 +1,211 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/focus/fake"
+	"main/internal/repository/sqlite"
+	"main/internal/usecase"
+)
+
+// focus 由假的焦點來源送出焦點改變，並等待追蹤器記錄
+func (env *testEnv) focus(t *testing.T, source *fake.Source, app string) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := env.clock.Now()
+	if err := source.Send(ctx, domain.FocusEvent{Focus: domain.Focus{App: app}, Time: now}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "記錄焦點 "+app, func() bool {
+		periods, err := env.repo.GetFocusBetween(ctx, now, now.Add(time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(periods) > 0 && periods[len(periods)-1].App == app && periods[len(periods)-1].Start.Equal(now)
+	})
+}
+
+func appDurations(stats []domain.DailyStats, day time.Time) (total time.Duration, apps map[string]time.Duration) {
+	apps = make(map[string]time.Duration)
+	for _, daily := range stats {
+		if !daily.Date.Equal(day) {
+			continue
+		}
+		for _, app := range daily.Apps {
+			apps[app.App] = app.Duration
+		}
+		return daily.TotalDuration, apps
+	}
+	return 0, apps
+}
+
+// TestFocusStatsExcludeManualActivities 確認手動新增或修改過的活動計入總時間，
+// 但不會分配到當時取得焦點的應用程式，進行中與已結束的活動都一樣
+func TestFocusStatsExcludeManualActivities(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(9, 0))
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+	editor := usecase.NewActivityEditor(env.repo, sqlite.NewSQLiteProjectRepository(env.db, env.clock), tracker, env.clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+	source := fake.New()
+	go tracker.TrackFocus(ctx, source)
+
+	// 09:00-09:30 在編輯器上使用滑鼠，09:10-09:20 同時使用鍵盤
+	env.focus(t, source, "editor")
+	env.work(t, at(9, 0), at(9, 9).Add(50*time.Second), 10*time.Second, domain.MouseActivity)
+	env.work(t, at(9, 10), at(9, 20), 10*time.Second, domain.MouseActivity, domain.KeyboardActivity)
+	env.work(t, at(9, 20).Add(10*time.Second), at(9, 30), 10*time.Second, domain.MouseActivity)
+	waitFor(t, "閾值到期", func() bool {
+		env.clock.Advance(time.Second)
+		return !tracker.IsActive()
+	})
+
+	// 鍵盤活動手動延長到 09:25，10:00-11:00 手動輸入離開電腦的會議，這段期間焦點仍在編輯器上
+	activities, err := editor.ActivitiesOn(ctx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, activity := range activities {
+		if activity.Type == domain.KeyboardActivity {
+			activity.SetEndTime(at(9, 25))
+			if err := editor.Edit(ctx, activity); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	env.clock.Set(at(11, 0))
+	meeting := domain.Activity{Type: domain.ManualActivity}
+	meeting.SetStartTime(at(10, 0))
+	meeting.SetEndTime(at(11, 0))
+	if _, err := editor.Add(ctx, meeting); err != nil {
+		t.Fatal(err)
+	}
+
+	// 11:00 起在瀏覽器上使用滑鼠，活動進行中時手動補上 11:02-11:06 的鍵盤活動
+	env.focus(t, source, "browser")
+	env.work(t, at(11, 0), at(11, 10), 10*time.Second, domain.MouseActivity)
+	typing := domain.Activity{Type: domain.KeyboardActivity}
+	typing.SetStartTime(at(11, 2))
+	typing.SetEndTime(at(11, 6))
+	if _, err := editor.Add(ctx, typing); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(name string, wantTotal time.Duration, wantApps map[string]time.Duration) {
+		t.Helper()
+
+		total, apps := appDurations(tracker.GetDailyStats(), day)
+		if total != wantTotal {
+			t.Errorf("%s: 總時間 = %v，應為 %v", name, total, wantTotal)
+		}
+		if len(apps) != len(wantApps) {
+			t.Errorf("%s: 應用程式時間 = %v，應為 %v", name, apps, wantApps)
+		}
+		for app, want := range wantApps {
+			if apps[app] != want {
+				t.Errorf("%s: %s 的時間 = %v，應為 %v", name, app, apps[app], want)
+			}
+		}
+	}
+
+	// 手動修改的鍵盤活動與滑鼠完全重疊，總時間只多了會議
+	check("進行中", 30*time.Minute+time.Hour+10*time.Minute+10*time.Second, map[string]time.Duration{
+		"editor":  30 * time.Minute,
+		"browser": 10*time.Minute + 10*time.Second,
+	})
+
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+	wantApps := map[string]time.Duration{
+		"editor":  30 * time.Minute,
+		"browser": 10 * time.Minute,
+	}
+	check("結束後", 30*time.Minute+time.Hour+10*time.Minute, wantApps)
+
+	if err := env.repo.RebuildRollups(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tracker.InvalidateStats()
+	check("重建彙總", 30*time.Minute+time.Hour+10*time.Minute, wantApps)
+}
+
+// TestFocusStatsOverMultipleDays 確認多天的統計中，每一天的滑鼠與鍵盤重疊時間
+// 都只算一次，應用程式與專案時間都等於當天的總時間。每天結束後都查詢一次，
+// 之後的活動結束時只重新彙總受影響的日期，較早的日期沿用快取。
+func TestFocusStatsOverMultipleDays(t *testing.T) {
+	first := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	days := []time.Time{first, first.AddDate(0, 0, 1), first.AddDate(0, 0, 2)}
+	at := func(day time.Time, hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(first, 9, 0))
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+	ids := newTestRules(t, env, domain.Rule{Project: "客戶 A", App: "Editor"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	check := func(name string, days []time.Time) {
+		t.Helper()
+
+		stats := tracker.GetDailyStats()
+		if len(stats) != len(days) {
+			t.Errorf("%s: 統計有 %d 天，應為 %d 天", name, len(stats), len(days))
+		}
+		for i, day := range days {
+			want := time.Duration(10*(i+1)) * time.Minute
+			date := name + " " + day.Format(time.DateOnly)
+			total, apps := appDurations(stats, day)
+			if total != want {
+				t.Errorf("%s: 總時間 = %v，應為 %v", date, total, want)
+			}
+			if len(apps) != 1 || apps["editor"] != want {
+				t.Errorf("%s: 應用程式時間 = %v，應為 editor %v", date, apps, want)
+			}
+			checkProjectDurations(t, date, projectDurations(stats, day), map[int64]time.Duration{
+				ids["客戶 A"]: want,
+			})
+		}
+	}
+
+	// 每天 09:00 起在編輯器上同時使用滑鼠與鍵盤，第 i 天持續 10*(i+1) 分鐘
+	env.switchFocus(t, "editor")
+	for i, day := range days {
+		env.work(t, at(day, 9, 0), at(day, 9, 10*(i+1)), 10*time.Second, domain.MouseActivity, domain.KeyboardActivity)
+		waitFor(t, "閾值到期", func() bool {
+			env.clock.Advance(time.Second)
+			return !tracker.IsActive()
+		})
+		check("第 "+day.Format(time.DateOnly)+" 天結束", days[:i+1])
+	}
+
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+	tracker.InvalidateStats()
+	check("重新彙總", days)
+}
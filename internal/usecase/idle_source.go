@@ -0,0 +1,21 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"main/internal/domain"
+)
+
+// IdleSource 回報作業系統判斷的閒置、螢幕鎖定與休眠事件，
+// 回傳的 channel 在來源結束或 ctx 結束時關閉
+type IdleSource interface {
+	Events(ctx context.Context) (<-chan domain.SystemEvent, error)
+}
+
+// ThresholdIdleSource 是依照沒有輸入的時間判斷閒置的來源，
+// ActivityTracker 監聽時會讓它的閾值跟著 UpdateThreshold 更新
+type ThresholdIdleSource interface {
+	IdleSource
+	UpdateThreshold(threshold time.Duration)
+}
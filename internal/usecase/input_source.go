@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"context"
+
+	"main/internal/domain"
+)
+
+// InputSource 產生正規化的輸入事件，回傳的 channel 在來源結束或 ctx 結束時關閉
+type InputSource interface {
+	Events(ctx context.Context) (<-chan domain.InputEvent, error)
+}
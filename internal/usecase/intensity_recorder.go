@@ -0,0 +1,166 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+	"main/pkg/clock"
+)
+
+// intensityFlushInterval 是將累計的輸入強度寫入資料庫的間隔
+const intensityFlushInterval = time.Minute
+
+// IntensityRecorder 在記憶體中累計每分鐘的輸入次數，定期寫入資料庫
+type IntensityRecorder struct {
+	mu      sync.Mutex
+	repo    repository.IntensityRepository
+	clock   clock.Clock
+	pending map[int64]*domain.InputIntensity // 尚未寫入的計數，以整分的 Unix 時間為鍵
+}
+
+func NewIntensityRecorder(repo repository.IntensityRepository, clk clock.Clock) *IntensityRecorder {
+	return &IntensityRecorder{
+		repo:    repo,
+		clock:   clk,
+		pending: make(map[int64]*domain.InputIntensity),
+	}
+}
+
+// Record 將一個輸入事件計入所在的分鐘
+func (r *IntensityRecorder) Record(ev domain.InputEvent) {
+	if ev.Kind == domain.InputOther {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	minute := ev.Time.Unix() - ev.Time.Unix()%60
+	counter, ok := r.pending[minute]
+	if !ok {
+		counter = &domain.InputIntensity{Minute: time.Unix(minute, 0)}
+		r.pending[minute] = counter
+	}
+	counter.Add(ev)
+}
+
+// Wrap 回傳一個在轉送事件前先記錄輸入強度的輸入來源
+func (r *IntensityRecorder) Wrap(source InputSource) InputSource {
+	return &recordingSource{source: source, recorder: r}
+}
+
+// Flush 將累計的計數寫入資料庫，寫入失敗時保留到下次再寫
+func (r *IntensityRecorder) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	pending := r.pending
+	r.pending = make(map[int64]*domain.InputIntensity)
+	r.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	counters := make([]domain.InputIntensity, 0, len(pending))
+	for _, minute := range slices.Sorted(maps.Keys(pending)) {
+		counters = append(counters, *pending[minute])
+	}
+
+	if err := r.repo.AddIntensity(ctx, counters); err != nil {
+		r.mu.Lock()
+		for minute, counter := range pending {
+			if current, ok := r.pending[minute]; ok {
+				counter.Merge(*current)
+			}
+			r.pending[minute] = counter
+		}
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run 每分鐘寫入一次累計的計數，ctx 結束時寫入剩下的計數
+func (r *IntensityRecorder) Run(ctx context.Context) error {
+	ticker := r.clock.NewTicker(intensityFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return r.Flush(context.Background())
+		case <-ticker.C():
+			if err := r.Flush(ctx); err != nil {
+				log.Printf("寫入輸入強度時發生錯誤: %v", err)
+			}
+		}
+	}
+}
+
+// Between 回傳 [from, to) 之間每分鐘的輸入強度，包含尚未寫入資料庫的計數
+func (r *IntensityRecorder) Between(ctx context.Context, from, to time.Time) ([]domain.InputIntensity, error) {
+	stored, err := r.repo.GetIntensity(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[int64]domain.InputIntensity, len(stored))
+	for _, counter := range stored {
+		merged[counter.Minute.Unix()] = counter
+	}
+
+	r.mu.Lock()
+	for minute, pending := range r.pending {
+		if minute < from.Unix()-from.Unix()%60 || minute >= to.Unix() {
+			continue
+		}
+		counter, ok := merged[minute]
+		if !ok {
+			counter.Minute = time.Unix(minute, 0).In(from.Location())
+		}
+		counter.Merge(*pending)
+		merged[minute] = counter
+	}
+	r.mu.Unlock()
+
+	result := make([]domain.InputIntensity, 0, len(merged))
+	for _, minute := range slices.Sorted(maps.Keys(merged)) {
+		result = append(result, merged[minute])
+	}
+	return result, nil
+}
+
+// recordingSource 將事件交給 recorder 計數後原樣轉送
+type recordingSource struct {
+	source   InputSource
+	recorder *IntensityRecorder
+}
+
+func (s *recordingSource) Events(ctx context.Context) (<-chan domain.InputEvent, error) {
+	in, err := s.source.Events(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan domain.InputEvent)
+	go func() {
+		defer close(out)
+
+		for ev := range in {
+			s.recorder.Record(ev)
+
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"main/internal/domain"
+)
+
+// PrivacyFilter 只讓白名單中的事件種類進入追蹤器與輸入強度記錄，
+// 白名單為 nil 時全部追蹤，為空的清單時全部不追蹤。未標示種類的事件只在白名單為 nil 時通過。
+type PrivacyFilter struct {
+	mu      sync.Mutex
+	allowed map[domain.InputKind]bool // nil 表示全部追蹤
+}
+
+func NewPrivacyFilter(kinds []domain.InputKind) *PrivacyFilter {
+	f := &PrivacyFilter{}
+	f.Update(kinds)
+	return f
+}
+
+// Update 更新白名單，kinds 為 nil 時全部追蹤，為空的清單時全部不追蹤
+func (f *PrivacyFilter) Update(kinds []domain.InputKind) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if kinds == nil {
+		f.allowed = nil
+		return
+	}
+	f.allowed = make(map[domain.InputKind]bool, len(kinds))
+	for _, kind := range kinds {
+		f.allowed[kind] = true
+	}
+}
+
+func (f *PrivacyFilter) Allow(ev domain.InputEvent) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.allowed == nil || f.allowed[ev.Kind]
+}
+
+// Wrap 回傳只轉送白名單中事件的輸入來源
+func (f *PrivacyFilter) Wrap(source InputSource) InputSource {
+	return &filteredSource{source: source, filter: f}
+}
+
+type filteredSource struct {
+	source InputSource
+	filter *PrivacyFilter
+}
+
+func (s *filteredSource) Events(ctx context.Context) (<-chan domain.InputEvent, error) {
+	in, err := s.source.Events(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan domain.InputEvent)
+	go func() {
+		defer close(out)
+
+		for ev := range in {
+			if !s.filter.Allow(ev) {
+				continue
+			}
+
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
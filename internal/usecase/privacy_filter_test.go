@@ -0,0 +1,179 @@
+package usecase_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/input/replay"
+	"main/internal/repository/sqlite"
+	"main/internal/usecase"
+)
+
+func TestPrivacyFilterTrackedKinds(t *testing.T) {
+	events := []domain.InputEvent{
+		{Type: domain.KeyboardActivity, Kind: domain.InputKeyPress},
+		{Type: domain.MouseActivity, Kind: domain.InputClick},
+		{Type: domain.MouseActivity, Kind: domain.InputOther},
+	}
+	tests := []struct {
+		name  string
+		kinds []domain.InputKind
+		want  []bool
+	}{
+		{"nil 全部追蹤", nil, []bool{true, true, true}},
+		{"空的清單全部不追蹤", []domain.InputKind{}, []bool{false, false, false}},
+		{"只追蹤點擊", []domain.InputKind{domain.InputClick}, []bool{false, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := usecase.NewPrivacyFilter(tt.kinds)
+			for i, ev := range events {
+				if got := filter.Allow(ev); got != tt.want[i] {
+					t.Errorf("Allow(%q) = %v，應為 %v", ev.Kind, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTrackedInputKindsSurviveSave 確認設定檔存檔後仍能分辨全部追蹤與全部不追蹤
+func TestTrackedInputKindsSurviveSave(t *testing.T) {
+	for _, kinds := range [][]domain.InputKind{nil, {}} {
+		data, err := json.Marshal(domain.Settings{TrackedInputKinds: kinds})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var loaded domain.Settings
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			t.Fatal(err)
+		}
+		if (loaded.TrackedInputKinds == nil) != (kinds == nil) || len(loaded.TrackedInputKinds) != len(kinds) {
+			t.Errorf("存檔前 %#v，讀回 %#v", kinds, loaded.TrackedInputKinds)
+		}
+	}
+}
+
+// TestInputEventHasNoKeyContent 確認輸入來源之後的事件型別沒有可以放按鍵內容的欄位
+func TestInputEventHasNoKeyContent(t *testing.T) {
+	allowed := map[string]bool{"Type": true, "Time": true, "Kind": true, "Distance": true}
+
+	fields := reflect.VisibleFields(reflect.TypeOf(domain.InputEvent{}))
+	for _, field := range fields {
+		if !allowed[field.Name] {
+			t.Errorf("domain.InputEvent 有未經審查的欄位 %s，不可加入能辨識按鍵的資訊", field.Name)
+		}
+	}
+}
+
+// TestKeyContentNeverStored 從帶有按鍵代碼與字元的重播檔輸入鍵盤事件，
+// 確認資料庫與記錄檔中都找不到按下的按鍵
+func TestKeyContentNeverStored(t *testing.T) {
+	const secret = "hunter2-secret"
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	env := newTestEnv(t, start)
+
+	var lines bytes.Buffer
+	for i, char := range secret {
+		fmt.Fprintf(&lines, `{"time":%q,"type":"keyboard","kind":"key_press","keychar":%q,"rawcode":%d,"text":%q}`+"\n",
+			start.Format(time.RFC3339), string(char), 0xbeef+i, secret)
+	}
+	path := filepath.Join(t.TempDir(), "keys.jsonl")
+	if err := os.WriteFile(path, lines.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(io.Discard)
+
+	ctx := context.Background()
+	intensity := usecase.NewIntensityRecorder(sqlite.NewSQLiteIntensityRepository(env.db), env.clock)
+	privacy := usecase.NewPrivacyFilter(nil)
+	if err := env.tracker.Track(ctx, intensity.Wrap(privacy.Wrap(replay.New(env.clock, path, 1)))); err != nil {
+		t.Fatal(err)
+	}
+	if err := intensity.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	intensities, err := sqlite.NewSQLiteIntensityRepository(env.db).GetIntensity(ctx, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(intensities) != 1 || intensities[0].KeyPresses != len(secret) {
+		t.Fatalf("輸入強度 = %+v，應記錄 %d 次按鍵", intensities, len(secret))
+	}
+
+	needles := []string{secret, fmt.Sprint(0xbeef), "keychar", "rawcode"}
+	for _, needle := range needles {
+		if strings.Contains(logs.String(), needle) {
+			t.Errorf("記錄檔中出現按鍵內容 %q:\n%s", needle, logs.String())
+		}
+	}
+
+	tables, err := env.db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for tables.Next() {
+		var name string
+		if err := tables.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := tables.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range names {
+		rows, err := env.db.QueryContext(ctx, `SELECT * FROM "`+name+`"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		columns, err := rows.Columns()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			pointers := make([]interface{}, len(columns))
+			for i := range values {
+				pointers[i] = &values[i]
+			}
+			if err := rows.Scan(pointers...); err != nil {
+				t.Fatal(err)
+			}
+			for i, value := range values {
+				cell := fmt.Sprint(value)
+				if b, ok := value.([]byte); ok {
+					cell = string(b)
+				}
+				for _, needle := range needles {
+					if strings.Contains(cell, needle) {
+						t.Errorf("資料表 %s 的欄位 %s 中出現按鍵內容 %q", name, columns[i], needle)
+					}
+				}
+			}
+		}
+		if err := rows.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+
+	"main/internal/domain"
+)
+
+const (
+	DefaultProfileName = "default"
+
+	// ProfileEnv 與 DatabaseEnv 可用環境變數指定設定檔與資料庫位置
+	ProfileEnv  = "WORKPULSE_PROFILE"
+	DatabaseEnv = "WORKPULSE_DB"
+
+	// LegacyDatabasePath 是舊版固定使用的資料庫位置，相對於工作目錄
+	LegacyDatabasePath = "workpulse.db"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Profile 是一組獨立的設定檔與資料庫，例如 "work" 與 "personal"。
+// 預設設定檔沿用 ~/.workpulse/settings.json，其他設定檔放在 ~/.workpulse/profiles/<name>/。
+type Profile struct {
+	Name      string
+	configDir string
+	dataDir   string
+}
+
+func NewProfile(name string) (Profile, error) {
+	if name == "" {
+		name = DefaultProfileName
+	}
+	if !profileNamePattern.MatchString(name) {
+		return Profile{}, fmt.Errorf("設定檔名稱只能包含英數字、- 與 _: %q", name)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return Profile{}, fmt.Errorf("無法取得使用者目錄: %v", err)
+	}
+
+	profile := Profile{
+		Name:      name,
+		configDir: filepath.Join(homeDir, ".workpulse"),
+		dataDir:   filepath.Join(userDataDir(homeDir), "workpulse"),
+	}
+	if name != DefaultProfileName {
+		profile.configDir = filepath.Join(profile.configDir, "profiles", name)
+		profile.dataDir = filepath.Join(profile.dataDir, "profiles", name)
+	}
+	return profile, nil
+}
+
+func (p Profile) SettingsPath() string {
+	return filepath.Join(p.configDir, "settings.json")
+}
+
+// RulesPath 回傳專案歸類規則檔的位置，與設定檔放在同一個目錄
+func (p Profile) RulesPath() string {
+	return filepath.Join(p.configDir, "rules.json")
+}
+
+// DefaultDatabasePath 回傳沒有其他指定時使用的資料庫位置
+func (p Profile) DefaultDatabasePath() string {
+	return filepath.Join(p.dataDir, "workpulse.db")
+}
+
+// ResolveDatabasePath 依序使用命令列參數、環境變數、設定檔中的路徑，最後才使用預設位置
+func (p Profile) ResolveDatabasePath(flagPath string, settings domain.Settings) string {
+	if flagPath != "" {
+		return flagPath
+	}
+	if envPath := os.Getenv(DatabaseEnv); envPath != "" {
+		return envPath
+	}
+	if settingsPath := settings.DatabasePath; settingsPath != "" {
+		return settingsPath
+	}
+	return p.DefaultDatabasePath()
+}
+
+// AdoptLegacyDatabase 回傳預設設定檔要使用的資料庫位置。
+// 預設位置還沒有資料庫、而工作目錄有舊版的 workpulse.db 時，將它與它的日誌檔搬到預設位置，
+// 升級後才不會看到空白的記錄。無法搬移時（例如跨檔案系統）回傳舊檔的位置繼續使用，並回傳原因。
+func (p Profile) AdoptLegacyDatabase() (string, error) {
+	path := p.DefaultDatabasePath()
+	if p.Name != DefaultProfileName || fileExists(path) || !fileExists(LegacyDatabasePath) {
+		return path, nil
+	}
+
+	legacy, err := filepath.Abs(LegacyDatabasePath)
+	if err != nil {
+		return LegacyDatabasePath, fmt.Errorf("無法取得舊的資料庫位置: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return legacy, fmt.Errorf("無法建立資料庫目錄，繼續使用 %s: %v", legacy, err)
+	}
+	if err := os.Rename(legacy, path); err != nil {
+		return legacy, fmt.Errorf("無法搬移舊的資料庫，繼續使用 %s: %v", legacy, err)
+	}
+	log.Printf("已將舊的資料庫 %s 搬到 %s", legacy, path)
+
+	// 異常結束時留下的日誌檔必須跟著資料庫，否則未完成的交易無法復原
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if !fileExists(legacy + suffix) {
+			continue
+		}
+		if err := os.Rename(legacy+suffix, path+suffix); err != nil {
+			log.Printf("搬移資料庫日誌檔 %s 失敗: %v", legacy+suffix, err)
+		}
+	}
+	return path, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return !errors.Is(err, fs.ErrNotExist)
+}
+
+// userDataDir 回傳使用者資料目錄，Linux 上遵循 XDG_DATA_HOME
+func userDataDir(homeDir string) string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir
+		}
+	case "darwin":
+		return filepath.Join(homeDir, "Library", "Application Support")
+	}
+	return filepath.Join(homeDir, ".local", "share")
+}
@@ -0,0 +1,87 @@
+package usecase_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"main/internal/usecase"
+)
+
+func newTestProfile(t *testing.T, name string) usecase.Profile {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	t.Chdir(t.TempDir())
+
+	profile, err := usecase.NewProfile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+func TestAdoptLegacyDatabaseMovesOldFile(t *testing.T) {
+	profile := newTestProfile(t, "")
+	if err := os.WriteFile(usecase.LegacyDatabasePath, []byte("legacy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := profile.AdoptLegacyDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != profile.DefaultDatabasePath() {
+		t.Fatalf("資料庫位置 = %s，應為 %s", path, profile.DefaultDatabasePath())
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "legacy" {
+		t.Fatalf("預設位置的資料庫 = %q, %v，應為搬過來的舊檔", data, err)
+	}
+	if _, err := os.Stat(usecase.LegacyDatabasePath); !os.IsNotExist(err) {
+		t.Errorf("舊檔應已搬走: %v", err)
+	}
+}
+
+func TestAdoptLegacyDatabaseKeepsExistingDatabase(t *testing.T) {
+	profile := newTestProfile(t, "")
+	path := profile.DefaultDatabasePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for file, data := range map[string]string{path: "current", usecase.LegacyDatabasePath: "legacy"} {
+		if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := profile.AdoptLegacyDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(got); string(data) != "current" {
+		t.Errorf("已有資料庫時不應覆蓋，讀到 %q", data)
+	}
+	if _, err := os.Stat(usecase.LegacyDatabasePath); err != nil {
+		t.Errorf("已有資料庫時舊檔應保留: %v", err)
+	}
+}
+
+func TestAdoptLegacyDatabaseIgnoresOtherProfiles(t *testing.T) {
+	profile := newTestProfile(t, "work")
+	if err := os.WriteFile(usecase.LegacyDatabasePath, []byte("legacy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := profile.AdoptLegacyDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != profile.DefaultDatabasePath() {
+		t.Errorf("資料庫位置 = %s，應為 %s", path, profile.DefaultDatabasePath())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("其他設定檔不應沿用舊的資料庫: %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+// ProjectService 管理專案與標籤，並決定新活動所屬的專案。
+// 切換目前的專案會保存到設定，並記錄在 editor 的復原歷程中。
+type ProjectService struct {
+	repo     repository.ProjectRepository
+	tracker  *ActivityTracker
+	settings *SettingsManager
+	editor   *ActivityEditor
+}
+
+func NewProjectService(repo repository.ProjectRepository, tracker *ActivityTracker, settings *SettingsManager, editor *ActivityEditor) *ProjectService {
+	return &ProjectService{
+		repo:     repo,
+		tracker:  tracker,
+		settings: settings,
+		editor:   editor,
+	}
+}
+
+// CreateProject 新增專案，名稱前後的空白會被移除
+func (s *ProjectService) CreateProject(ctx context.Context, name string) (domain.Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.Project{}, fmt.Errorf("專案名稱不可為空白")
+	}
+	return s.repo.CreateProject(ctx, name)
+}
+
+func (s *ProjectService) Project(ctx context.Context, id int64) (domain.Project, error) {
+	return s.repo.GetProject(ctx, id)
+}
+
+func (s *ProjectService) Projects(ctx context.Context) ([]domain.Project, error) {
+	return s.repo.GetProjects(ctx)
+}
+
+// CreateTag 新增標籤，名稱前後的空白會被移除
+func (s *ProjectService) CreateTag(ctx context.Context, name string) (domain.Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return domain.Tag{}, fmt.Errorf("標籤名稱不可為空白")
+	}
+	return s.repo.CreateTag(ctx, name)
+}
+
+func (s *ProjectService) Tags(ctx context.Context) ([]domain.Tag, error) {
+	return s.repo.GetTags(ctx)
+}
+
+func (s *ProjectService) ActivityTags(ctx context.Context, activityID int64) ([]domain.Tag, error) {
+	return s.repo.GetActivityTags(ctx, activityID)
+}
+
+// LoadCurrentProject 以設定中保存的專案作為目前的專案，啟動時使用，不記錄在復原歷程中
+func (s *ProjectService) LoadCurrentProject(ctx context.Context) error {
+	return s.setCurrentProject(ctx, s.settings.GetSettings().CurrentProjectID)
+}
+
+// SetCurrentProject 設定之後由 StartActivity 開始的活動所屬的專案並保存到設定，0 表示沒有專案
+func (s *ProjectService) SetCurrentProject(ctx context.Context, projectID int64) error {
+	previous := s.tracker.CurrentProject()
+	if projectID == previous {
+		return nil
+	}
+	if err := s.switchProject(ctx, projectID); err != nil {
+		return err
+	}
+
+	s.editor.record("切換目前的專案",
+		func(ctx context.Context) error { return s.switchProject(ctx, previous) },
+		func(ctx context.Context) error { return s.switchProject(ctx, projectID) },
+	)
+	return nil
+}
+
+// switchProject 設定目前的專案並保存到設定
+func (s *ProjectService) switchProject(ctx context.Context, projectID int64) error {
+	if err := s.setCurrentProject(ctx, projectID); err != nil {
+		return err
+	}
+
+	settings := s.settings.GetSettings()
+	settings.CurrentProjectID = projectID
+	return s.settings.UpdateSettings(settings)
+}
+
+func (s *ProjectService) setCurrentProject(ctx context.Context, projectID int64) error {
+	if projectID != 0 {
+		if _, err := s.repo.GetProject(ctx, projectID); err != nil {
+			return err
+		}
+	}
+	return s.tracker.SetCurrentProject(projectID)
+}
+
+// CurrentProject 回傳目前的專案，沒有專案時回傳 ID 為 0 的專案
+func (s *ProjectService) CurrentProject(ctx context.Context) (domain.Project, error) {
+	projectID := s.tracker.CurrentProject()
+	if projectID == 0 {
+		return domain.Project{}, nil
+	}
+	return s.repo.GetProject(ctx, projectID)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+	"main/pkg/clock"
+)
+
+// retentionInterval 是自動執行保存政策的間隔
+const retentionInterval = 24 * time.Hour
+
+// RetentionService 依照設定中的保存政策定期合併與刪除舊活動
+type RetentionService struct {
+	repo     repository.ActivityRepository
+	settings *SettingsManager
+	tracker  *ActivityTracker
+	clock    clock.Clock
+}
+
+func NewRetentionService(repo repository.ActivityRepository, settings *SettingsManager, tracker *ActivityTracker, clk clock.Clock) *RetentionService {
+	return &RetentionService{
+		repo:     repo,
+		settings: settings,
+		tracker:  tracker,
+		clock:    clk,
+	}
+}
+
+// Plan 依照目前的設定與時間算出這次執行的界線，界線對齊每日邊界
+func (s *RetentionService) Plan() domain.RetentionPlan {
+	settings := s.settings.GetSettings()
+	policy := settings.Retention
+	cal := s.tracker.Calendar()
+	now := s.clock.Now()
+
+	var plan domain.RetentionPlan
+	if policy.CompactAfterDays > 0 {
+		plan.CompactBefore = cal.DayStart(now.AddDate(0, 0, -policy.CompactAfterDays))
+		plan.MergeGap = time.Duration(settings.SessionGapSeconds) * time.Second
+	}
+	if policy.PurgeAfterMonths > 0 {
+		plan.PurgeBefore = cal.DayStart(now.AddDate(0, -policy.PurgeAfterMonths, 0))
+	}
+	return plan
+}
+
+// Apply 執行一次保存政策，dryRun 時只回報會變更的記錄數
+func (s *RetentionService) Apply(ctx context.Context, dryRun bool) (domain.RetentionReport, error) {
+	plan := s.Plan()
+	if plan.CompactBefore.IsZero() && plan.PurgeBefore.IsZero() {
+		return domain.RetentionReport{DryRun: dryRun, Plan: plan}, nil
+	}
+
+	report, err := s.repo.ApplyRetention(ctx, plan, dryRun)
+	if err != nil {
+		return report, err
+	}
+
+	logRetentionReport(report)
+	if !dryRun {
+		s.tracker.InvalidateStats()
+	}
+	return report, nil
+}
+
+// Run 啟動時先執行一次，之後每隔 retentionInterval 執行，直到 ctx 結束
+func (s *RetentionService) Run(ctx context.Context) error {
+	ticker := s.clock.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Apply(ctx, false); err != nil {
+			log.Printf("執行保存政策時發生錯誤: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+func logRetentionReport(report domain.RetentionReport) {
+	prefix := "保存政策"
+	if report.DryRun {
+		prefix = "保存政策（試執行）"
+	}
+
+	if !report.Plan.PurgeBefore.IsZero() {
+		log.Printf("%s: 刪除 %s 之前結束的活動 %d 筆",
+			prefix,
+			report.Plan.PurgeBefore.Format(time.RFC3339),
+			report.PurgedActivities)
+	}
+	if !report.Plan.CompactBefore.IsZero() {
+		log.Printf("%s: 合併 %s 之前結束、間隔不超過 %v 的活動, 檢查 %d 筆, 合併成 %d 筆, 刪除 %d 筆",
+			prefix,
+			report.Plan.CompactBefore.Format(time.RFC3339),
+			report.Plan.MergeGap,
+			report.ScannedRows,
+			report.Sessions,
+			report.MergedRows)
+	}
+}
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository"
+)
+
+// RuleEngine 依規則檔將焦點時的活動歸類到專案，依檔案中的順序採用第一條符合的規則。
+// 規則以名稱指定專案，載入時對應到 projects 中的專案，不存在的專案會自動建立。
+// 規則可能在 UI 重新載入時被追蹤器同時讀取，由 mu 保護。
+type RuleEngine struct {
+	mu        sync.Mutex
+	rules     []compiledRule
+	rulesPath string
+	projects  repository.ProjectRepository
+}
+
+// compiledRule 是解析過正規表示式與時間，並對應到專案的規則
+type compiledRule struct {
+	domain.Rule
+	project  domain.Project
+	title    *regexp.Regexp
+	from, to int // 從午夜起算的分鐘數，兩者相同表示不限時間
+}
+
+func NewRuleEngine(profile Profile, projects repository.ProjectRepository) *RuleEngine {
+	return &RuleEngine{rulesPath: profile.RulesPath(), projects: projects}
+}
+
+// Path 回傳規則檔的位置
+func (e *RuleEngine) Path() string {
+	return e.rulesPath
+}
+
+// Load 重新讀取規則檔，檔案不存在時建立空白的規則檔。
+// 有任何規則無法解析時保留原本的規則。
+func (e *RuleEngine) Load(ctx context.Context) error {
+	data, err := os.ReadFile(e.rulesPath)
+	if os.IsNotExist(err) {
+		e.Update(ctx, nil)
+		return e.saveEmpty()
+	}
+	if err != nil {
+		return err
+	}
+
+	var rules []domain.Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return fmt.Errorf("無法解析規則檔 %s: %v", e.rulesPath, err)
+	}
+	return e.Update(ctx, rules)
+}
+
+func (e *RuleEngine) saveEmpty() error {
+	if err := os.MkdirAll(filepath.Dir(e.rulesPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(e.rulesPath, []byte("[]\n"), 0644)
+}
+
+// Update 以 rules 取代目前的規則，所有規則都能解析後才建立缺少的專案
+func (e *RuleEngine) Update(ctx context.Context, rules []domain.Rule) error {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			return fmt.Errorf("第 %d 條規則錯誤: %v", i+1, err)
+		}
+		compiled = append(compiled, c)
+	}
+	if err := e.resolveProjects(ctx, compiled); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = compiled
+	return nil
+}
+
+// resolveProjects 依名稱找出規則指定的專案，不存在時建立
+func (e *RuleEngine) resolveProjects(ctx context.Context, rules []compiledRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	existing, err := e.projects.GetProjects(ctx)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]domain.Project, len(existing))
+	for _, project := range existing {
+		byName[project.Name] = project
+	}
+
+	for i := range rules {
+		project, ok := byName[rules[i].Project]
+		if !ok {
+			if project, err = e.projects.CreateProject(ctx, rules[i].Project); err != nil {
+				return fmt.Errorf("建立規則的專案 %q 失敗: %v", rules[i].Project, err)
+			}
+			byName[project.Name] = project
+		}
+		rules[i].project = project
+	}
+	return nil
+}
+
+func compileRule(rule domain.Rule) (compiledRule, error) {
+	rule.Project = strings.TrimSpace(rule.Project)
+	c := compiledRule{Rule: rule}
+	if rule.Project == "" {
+		return c, fmt.Errorf("沒有指定專案")
+	}
+
+	if rule.Title != "" {
+		title, err := regexp.Compile(rule.Title)
+		if err != nil {
+			return c, fmt.Errorf("視窗標題的正規表示式錯誤: %v", err)
+		}
+		c.title = title
+	}
+
+	if rule.From != "" || rule.To != "" {
+		var err error
+		if c.from, err = domain.ParseClock(rule.From); err != nil {
+			return c, err
+		}
+		if c.to, err = domain.ParseClock(rule.To); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// Classify 回傳 at 時取得焦點的 focus 上的活動所屬的專案，沒有符合的規則時回傳 ID 為 0 的專案。
+// 時間條件以 at 本身的時區判斷。
+func (e *RuleEngine) Classify(focus domain.Focus, at time.Time) domain.Project {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, rule := range e.rules {
+		if rule.match(focus, at) {
+			return rule.project
+		}
+	}
+	return domain.Project{}
+}
+
+// NextChange 回傳 at 之後最早可能因時間或星期條件改變歸類結果的時間，以 at 本身的時區計算。
+// 沒有規則限制時間或星期時回傳零值。
+func (e *RuleEngine) NextChange(at time.Time) time.Time {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var next time.Time
+	consider := func(minute int) {
+		t := time.Date(at.Year(), at.Month(), at.Day(), 0, minute, 0, 0, at.Location())
+		if !t.After(at) {
+			t = time.Date(at.Year(), at.Month(), at.Day()+1, 0, minute, 0, 0, at.Location())
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	for _, rule := range e.rules {
+		if rule.from != rule.to {
+			consider(rule.from)
+			consider(rule.to)
+		}
+		if len(rule.Weekdays) > 0 {
+			consider(0)
+		}
+	}
+	return next
+}
+
+func (r compiledRule) match(focus domain.Focus, at time.Time) bool {
+	if r.App != "" && !strings.EqualFold(r.App, focus.App) {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(focus.Title) {
+		return false
+	}
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, at.Weekday()) {
+		return false
+	}
+	if r.from != r.to {
+		minute := at.Hour()*60 + at.Minute()
+		if r.from < r.to {
+			return minute >= r.from && minute < r.to
+		}
+		return minute >= r.from || minute < r.to
+	}
+	return true
+}
@@ -0,0 +1,363 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+	"main/internal/repository/sqlite"
+	"main/internal/usecase"
+)
+
+// newTestRules 建立以 rules 歸類的規則引擎並交給追蹤器，回傳規則建立的各專案 ID
+func newTestRules(t *testing.T, env *testEnv, rules ...domain.Rule) map[string]int64 {
+	t.Helper()
+
+	ctx := context.Background()
+	projects := sqlite.NewSQLiteProjectRepository(env.db, env.clock)
+	engine := usecase.NewRuleEngine(newTestProfile(t, ""), projects)
+	if err := engine.Update(ctx, rules); err != nil {
+		t.Fatal(err)
+	}
+	env.tracker.UpdateRules(engine)
+
+	existing, err := projects.GetProjects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make(map[string]int64)
+	for _, project := range existing {
+		ids[project.Name] = project.ID
+	}
+	for _, rule := range rules {
+		if ids[rule.Project] == 0 {
+			t.Fatalf("規則的專案 %q 沒有建立", rule.Project)
+		}
+	}
+	return ids
+}
+
+// switchFocus 讓追蹤器在目前的時間切換到 app
+func (env *testEnv) switchFocus(t *testing.T, app string) {
+	t.Helper()
+
+	if err := env.tracker.HandleFocus(domain.FocusEvent{Focus: domain.Focus{App: app}, Time: env.clock.Now()}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func projectDurations(stats []domain.DailyStats, day time.Time) map[int64]time.Duration {
+	projects := make(map[int64]time.Duration)
+	for _, daily := range stats {
+		if daily.Date.Equal(day) {
+			for _, project := range daily.Projects {
+				projects[project.ProjectID] = project.Duration
+			}
+		}
+	}
+	return projects
+}
+
+func checkProjectDurations(t *testing.T, name string, got, want map[int64]time.Duration) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("%s: 專案時間 = %v，應為 %v", name, got, want)
+		return
+	}
+	for id, d := range want {
+		if got[id] != d {
+			t.Errorf("%s: 專案 %d 的時間 = %v，應為 %v", name, id, got[id], d)
+		}
+	}
+}
+
+// TestRulesAssignProjectToActivities 確認符合規則的活動屬於規則的專案，其他活動屬於目前的專案，
+// 每日統計依活動的專案計算
+func TestRulesAssignProjectToActivities(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(9, 0))
+	ctx := context.Background()
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+
+	ids := newTestRules(t, env, domain.Rule{Project: "客戶 A", App: "Editor"})
+	internal, err := sqlite.NewSQLiteProjectRepository(env.db, env.clock).CreateProject(ctx, "內部")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.SetCurrentProject(internal.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(runCtx) }()
+
+	env.switchFocus(t, "editor")
+	env.work(t, at(9, 0), at(9, 30), 10*time.Second, domain.MouseActivity, domain.KeyboardActivity)
+	waitFor(t, "閾值到期", func() bool {
+		env.clock.Advance(time.Second)
+		return !tracker.IsActive()
+	})
+	env.clock.Set(at(9, 32))
+	env.switchFocus(t, "browser")
+	env.work(t, at(9, 32), at(10, 0), 10*time.Second, domain.MouseActivity)
+
+	checkProjectDurations(t, "進行中", projectDurations(tracker.GetDailyStats(), day), map[int64]time.Duration{
+		ids["客戶 A"]: 30 * time.Minute,
+		internal.ID: 28*time.Minute + 10*time.Second,
+	})
+
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]time.Duration{
+		ids["客戶 A"]: 30 * time.Minute,
+		internal.ID: 28 * time.Minute,
+	}
+	checkProjectDurations(t, "結束後", projectDurations(tracker.GetDailyStats(), day), want)
+
+	activities, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, activity := range activities {
+		wantProject := internal.ID
+		if activity.StartTime().Before(at(9, 30)) {
+			wantProject = ids["客戶 A"]
+		}
+		if activity.ProjectID != wantProject {
+			t.Errorf("%v 開始的活動專案 = %d，應為 %d", activity.StartTime(), activity.ProjectID, wantProject)
+		}
+	}
+}
+
+// TestApplyRulesReclassifiesTrackedActivities 確認套用規則會改變已記錄活動的專案並寫入稽核記錄，
+// 手動輸入的活動與沒有符合規則的活動保留原本的專案
+func TestApplyRulesReclassifiesTrackedActivities(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(9, 0))
+	ctx := context.Background()
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+
+	env.switchFocus(t, "editor")
+	env.work(t, at(9, 0), at(9, 30), 10*time.Second, domain.MouseActivity)
+	env.clock.Set(at(9, 32))
+	env.switchFocus(t, "browser")
+	env.work(t, at(9, 32), at(10, 0), 10*time.Second, domain.MouseActivity)
+	env.clock.Set(at(10, 2))
+	env.switchFocus(t, "terminal")
+	env.work(t, at(10, 2), at(10, 10), 10*time.Second, domain.KeyboardActivity)
+	if err := tracker.StopActivity(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 焦點仍在瀏覽器上時手動輸入的會議
+	env.clock.Set(at(11, 0))
+	env.switchFocus(t, "browser")
+	if _, err := newTestEditor(env).Add(ctx, manualActivity(at(10, 30), at(11, 0))); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := newTestRules(t, env,
+		domain.Rule{Project: "客戶 A", App: "editor"},
+		domain.Rule{Project: "客戶 B", App: "browser"},
+	)
+	changed, err := tracker.ApplyRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("重新歸類 %d 筆活動，應為 2 筆", changed)
+	}
+
+	checkProjectDurations(t, "套用規則後", projectDurations(tracker.GetDailyStats(), day), map[int64]time.Duration{
+		ids["客戶 A"]: 30 * time.Minute,
+		ids["客戶 B"]: 28 * time.Minute,
+		0:           8*time.Minute + 30*time.Minute,
+	})
+
+	var audited int
+	for _, entry := range env.auditLog(t) {
+		if entry.Source != domain.AuditRules {
+			continue
+		}
+		audited++
+		if entry.Operation != domain.AuditUpdate || entry.Before == nil || entry.After == nil ||
+			entry.Before.ProjectID != 0 || entry.After.ProjectID == 0 {
+			t.Errorf("套用規則的稽核記錄 = %+v", entry)
+		}
+	}
+	if audited != changed {
+		t.Errorf("套用規則寫入 %d 筆稽核記錄，應為 %d 筆", audited, changed)
+	}
+
+	if changed, err := tracker.ApplyRules(ctx); err != nil || changed != 0 {
+		t.Errorf("再次套用規則 = %d, %v，應沒有變更", changed, err)
+	}
+}
+
+// morningRules 在編輯器上 08:00-09:00 歸類到晨會，09:00-12:00 歸類到客戶，其他應用程式不歸類
+var morningRules = []domain.Rule{
+	{Project: "晨會", App: "editor", From: "08:00", To: "09:00"},
+	{Project: "客戶", App: "editor", From: "09:00", To: "12:00"},
+}
+
+// TestRulesSplitActivityWhenProjectChanges 確認進行中的活動在規則的時間條件或焦點改變而換專案時結束，
+// 08:50 開始的活動不會整段歸類到 08:50 符合的規則
+func TestRulesSplitActivityWhenProjectChanges(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(8, 50))
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+	ids := newTestRules(t, env, morningRules...)
+
+	env.switchFocus(t, "editor")
+	env.work(t, at(8, 50), at(9, 59).Add(50*time.Second), 10*time.Second, domain.MouseActivity)
+	env.clock.Set(at(10, 0))
+	env.switchFocus(t, "browser")
+	env.work(t, at(10, 0), at(12, 0), 10*time.Second, domain.MouseActivity)
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	checkProjectDurations(t, "結束後", projectDurations(tracker.GetDailyStats(), day), map[int64]time.Duration{
+		ids["晨會"]: 9*time.Minute + 50*time.Second,
+		ids["客戶"]: 59*time.Minute + 50*time.Second,
+		0:         2 * time.Hour,
+	})
+
+	// 即時分段後的活動已符合規則，套用規則不需要再分割
+	if changed, err := tracker.ApplyRules(context.Background()); err != nil || changed != 0 {
+		t.Errorf("套用規則 = %d, %v，應沒有變更", changed, err)
+	}
+}
+
+// TestApplyRulesSplitsActivityAtBoundaries 確認套用規則時，跨過規則時間條件與焦點改變的活動
+// 在改變的時間分割，各段屬於當時符合的專案並保留標籤，每段都寫入稽核記錄
+func TestApplyRulesSplitsActivityAtBoundaries(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(8, 50))
+	ctx := context.Background()
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+
+	// 沒有規則時 08:50-12:00 是一筆連續的活動
+	env.switchFocus(t, "editor")
+	env.work(t, at(8, 50), at(9, 59).Add(50*time.Second), 10*time.Second, domain.MouseActivity)
+	env.clock.Set(at(10, 0))
+	env.switchFocus(t, "browser")
+	env.work(t, at(10, 0), at(12, 0), 10*time.Second, domain.MouseActivity)
+	if err := tracker.StopActivity(); err != nil {
+		t.Fatal(err)
+	}
+
+	activities, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 {
+		t.Fatalf("套用規則前有 %d 筆活動，應為 1 筆", len(activities))
+	}
+	projects := sqlite.NewSQLiteProjectRepository(env.db, env.clock)
+	tag, err := projects.CreateTag(ctx, "計費")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.SetActivityTags(ctx, activities[0].ID, []int64{tag.ID}, domain.AuditManual); err != nil {
+		t.Fatal(err)
+	}
+
+	ids := newTestRules(t, env, morningRules...)
+	changed, err := tracker.ApplyRules(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 1 {
+		t.Errorf("重新歸類 %d 筆活動，應為 1 筆", changed)
+	}
+
+	activities, err = tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	type part struct {
+		start, end time.Time
+		projectID  int64
+	}
+	want := []part{
+		{at(8, 50), at(9, 0), ids["晨會"]},
+		{at(9, 0), at(10, 0), ids["客戶"]},
+		{at(10, 0), at(12, 0), 0},
+	}
+	if len(activities) != len(want) {
+		t.Fatalf("套用規則後有 %d 筆活動，應為 %d 筆", len(activities), len(want))
+	}
+	for i, activity := range activities {
+		got := part{activity.StartTime(), activity.EndTime(), activity.ProjectID}
+		if !got.start.Equal(want[i].start) || !got.end.Equal(want[i].end) || got.projectID != want[i].projectID {
+			t.Errorf("第 %d 段 = %+v，應為 %+v", i+1, got, want[i])
+		}
+		if activity.Manual {
+			t.Errorf("第 %d 段被標示為手動修改", i+1)
+		}
+		tags, err := projects.GetActivityTags(ctx, activity.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags) != 1 || tags[0].ID != tag.ID {
+			t.Errorf("第 %d 段的標籤 = %+v，應保留 %+v", i+1, tags, tag)
+		}
+	}
+
+	checkProjectDurations(t, "套用規則後", projectDurations(tracker.GetDailyStats(), day), map[int64]time.Duration{
+		ids["晨會"]: 10 * time.Minute,
+		ids["客戶"]: time.Hour,
+		0:         2 * time.Hour,
+	})
+	if err := env.repo.RebuildRollups(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tracker.InvalidateStats()
+	if total, _ := appDurations(tracker.GetDailyStats(), day); total != 3*time.Hour+10*time.Minute {
+		t.Errorf("重建彙總後總時間 = %v，應為 3h10m", total)
+	}
+
+	var operations []domain.AuditOperation
+	for _, entry := range env.auditLog(t) {
+		if entry.Source == domain.AuditRules {
+			operations = append(operations, entry.Operation)
+		}
+	}
+	if len(operations) != 3 || operations[0] != domain.AuditUpdate ||
+		operations[1] != domain.AuditInsert || operations[2] != domain.AuditInsert {
+		t.Errorf("套用規則的稽核記錄 = %v，應為一筆修改與兩筆新增", operations)
+	}
+
+	if changed, err := tracker.ApplyRules(ctx); err != nil || changed != 0 {
+		t.Errorf("再次套用規則 = %d, %v，應沒有變更", changed, err)
+	}
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"sort"
+	"time"
+
+	"main/internal/domain"
+)
+
+// BuildSessions 將間隔小於 gap 的活動合併成工作階段，重疊的滑鼠與鍵盤記錄會併入同一個工作階段。
+// 尚未結束的活動以 openEnd 作為結束時間。
+func BuildSessions(activities []domain.Activity, gap time.Duration, openEnd time.Time) []domain.Session {
+	sorted := append([]domain.Activity(nil), activities...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].StartTimeUnix < sorted[j].StartTimeUnix
+	})
+
+	var sessions []domain.Session
+	for _, activity := range sorted {
+		start := activity.StartTime()
+		end := activity.EndTime()
+		inProgress := !activity.IsEnded()
+		if inProgress {
+			end = openEnd
+		}
+		if end.Before(start) {
+			end = start
+		}
+
+		if n := len(sessions); n > 0 && start.Sub(sessions[n-1].End) < gap {
+			session := &sessions[n-1]
+			if end.After(session.End) {
+				session.End = end
+			}
+			addSegment(session, activity, end.Sub(start), inProgress)
+			continue
+		}
+
+		session := domain.Session{Start: start, End: end}
+		addSegment(&session, activity, end.Sub(start), inProgress)
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func addSegment(session *domain.Session, activity domain.Activity, duration time.Duration, inProgress bool) {
+	session.Segments = append(session.Segments, activity)
+	session.InProgress = session.InProgress || inProgress
+	switch activity.Type {
+	case domain.MouseActivity:
+		session.MouseDuration += duration
+	case domain.ManualActivity:
+		session.ManualDuration += duration
+	default:
+		session.KeyboardDuration += duration
+	}
+}
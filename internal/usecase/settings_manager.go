@@ -4,29 +4,35 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"main/internal/domain"
 )
 
+// SettingsManager 的設定可能同時被 UI 與背景工作讀寫，由 mu 保護
 type SettingsManager struct {
+	mu           sync.Mutex
 	settings     domain.Settings
 	settingsPath string
 }
 
-func NewSettingsManager() *SettingsManager {
-	homeDir, _ := os.UserHomeDir()
+func NewSettingsManager(profile Profile) *SettingsManager {
 	return &SettingsManager{
 		settings: domain.Settings{
-			ThresholdSeconds: 15,
+			ThresholdSeconds:  15,
+			SessionGapSeconds: 60,
 		},
-		settingsPath: filepath.Join(homeDir, ".workpulse", "settings.json"),
+		settingsPath: profile.SettingsPath(),
 	}
 }
 
 func (m *SettingsManager) LoadSettings() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	data, err := os.ReadFile(m.settingsPath)
 	if os.IsNotExist(err) {
-		return m.SaveSettings()
+		return m.saveSettingsLocked()
 	}
 	if err != nil {
 		return err
@@ -35,6 +41,13 @@ func (m *SettingsManager) LoadSettings() error {
 }
 
 func (m *SettingsManager) SaveSettings() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.saveSettingsLocked()
+}
+
+func (m *SettingsManager) saveSettingsLocked() error {
 	dir := filepath.Dir(m.settingsPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
@@ -48,10 +61,16 @@ func (m *SettingsManager) SaveSettings() error {
 }
 
 func (m *SettingsManager) GetSettings() domain.Settings {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	return m.settings
 }
 
 func (m *SettingsManager) UpdateSettings(settings domain.Settings) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.settings = settings
-	return m.SaveSettings()
-} 
\ No newline at end of file
+	return m.saveSettingsLocked()
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+)
+
+// undoLimit 是最多保留的可復原步驟數，超過時捨棄最舊的步驟
+const undoLimit = 100
+
+var (
+	ErrNothingToUndo = errors.New("沒有可復原的操作")
+	ErrNothingToRedo = errors.New("沒有可重做的操作")
+)
+
+// undoAction 是一次可復原的使用者變更，undo 與 redo 需能交替執行多次
+type undoAction struct {
+	label string
+	undo  func(ctx context.Context) error
+	redo  func(ctx context.Context) error
+}
+
+// activityRef 指向一筆活動目前的 ID。
+// 復原刪除會以新的 ID 重新加入活動，同一筆活動的所有步驟共用一個 activityRef，之後的步驟才找得到它。
+type activityRef struct {
+	id int64
+}
+
+// undoHistory 保存可復原與可重做的步驟，呼叫者需自行同步
+type undoHistory struct {
+	done   []undoAction
+	undone []undoAction
+	refs   map[int64]*activityRef
+}
+
+func newUndoHistory() *undoHistory {
+	return &undoHistory{refs: make(map[int64]*activityRef)}
+}
+
+// ref 回傳 ID 目前為 id 的活動的 activityRef
+func (h *undoHistory) ref(id int64) *activityRef {
+	if ref, ok := h.refs[id]; ok {
+		return ref
+	}
+	ref := &activityRef{id: id}
+	h.refs[id] = ref
+	return ref
+}
+
+// rename 在活動以新的 ID 重新加入後更新 ref
+func (h *undoHistory) rename(ref *activityRef, id int64) {
+	delete(h.refs, ref.id)
+	ref.id = id
+	h.refs[id] = ref
+}
+
+// forget 在活動刪除後移除 ref 的對應，ref 本身仍保留給復原時使用
+func (h *undoHistory) forget(ref *activityRef) {
+	delete(h.refs, ref.id)
+}
+
+// push 記錄新的步驟，新的變更之後無法再重做先前復原的步驟
+func (h *undoHistory) push(action undoAction) {
+	h.done = append(h.done, action)
+	if len(h.done) > undoLimit {
+		h.done = h.done[len(h.done)-undoLimit:]
+	}
+	h.undone = nil
+}
+
+// undo 復原最近一個步驟並回傳它的名稱，失敗時步驟留在原處
+func (h *undoHistory) undo(ctx context.Context) (string, error) {
+	if len(h.done) == 0 {
+		return "", ErrNothingToUndo
+	}
+	action := h.done[len(h.done)-1]
+	if err := action.undo(ctx); err != nil {
+		return action.label, err
+	}
+	h.done = h.done[:len(h.done)-1]
+	h.undone = append(h.undone, action)
+	return action.label, nil
+}
+
+// redo 重做最近復原的步驟並回傳它的名稱，失敗時步驟留在原處
+func (h *undoHistory) redo(ctx context.Context) (string, error) {
+	if len(h.undone) == 0 {
+		return "", ErrNothingToRedo
+	}
+	action := h.undone[len(h.undone)-1]
+	if err := action.redo(ctx); err != nil {
+		return action.label, err
+	}
+	h.undone = h.undone[:len(h.undone)-1]
+	h.done = append(h.done, action)
+	return action.label, nil
+}
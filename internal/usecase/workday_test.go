@@ -0,0 +1,136 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"main/internal/domain"
+)
+
+// work 從 from 到 to 每隔 every 送出一次 types 的輸入，結束時時鐘停在最後一次輸入之後 every
+func (env *testEnv) work(t *testing.T, from, to time.Time, every time.Duration, types ...domain.ActivityType) {
+	t.Helper()
+
+	env.clock.Set(from)
+	for !env.clock.Now().After(to) {
+		for _, activityType := range types {
+			if err := env.tracker.StartActivity(activityType); err != nil {
+				t.Fatalf("StartActivity(%v) at %v: %v", activityType, env.clock.Now(), err)
+			}
+		}
+		env.clock.Advance(every)
+	}
+}
+
+// TestWorkday 以假時鐘模擬一整天的輸入：上午滑鼠與鍵盤重疊、休息後閾值到期、
+// 晚上的活動跨過午夜，並檢查每日統計與彙總重建的結果相同
+func TestWorkday(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+
+	env := newTestEnv(t, at(9, 0))
+	tracker := env.tracker
+	tracker.UpdateThreshold(60)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runErr := make(chan error, 1)
+	go func() { runErr <- tracker.Run(ctx) }()
+
+	// 09:00-10:00 滑鼠，09:30 起同時使用鍵盤
+	env.work(t, at(9, 0), at(9, 29).Add(50*time.Second), 10*time.Second, domain.MouseActivity)
+	env.work(t, at(9, 30), at(10, 0), 10*time.Second, domain.MouseActivity, domain.KeyboardActivity)
+
+	// 休息 30 分鐘，兩種活動都在最後一次輸入時結束
+	env.clock.Set(at(10, 30))
+	waitFor(t, "休息時閾值到期", func() bool {
+		env.clock.Advance(time.Second)
+		return !tracker.IsActive()
+	})
+
+	// 10:30-12:00 只用鍵盤
+	env.work(t, at(10, 30), at(12, 0), 20*time.Second, domain.KeyboardActivity)
+
+	// 23:30 到隔天 00:30 的活動跨過午夜
+	env.work(t, at(23, 30), at(24, 30), 30*time.Second, domain.MouseActivity)
+	waitFor(t, "午夜後閾值到期", func() bool {
+		env.clock.Advance(time.Second)
+		return !tracker.IsActive()
+	})
+
+	activities, err := env.repo.GetActivities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type span struct {
+		activityType domain.ActivityType
+		start, end   time.Time
+	}
+	want := []span{
+		{domain.MouseActivity, at(9, 0), at(10, 0)},
+		{domain.KeyboardActivity, at(9, 30), at(10, 0)},
+		{domain.KeyboardActivity, at(10, 30), at(12, 0)},
+		{domain.MouseActivity, at(23, 30), at(24, 30)},
+	}
+	if len(activities) != len(want) {
+		t.Fatalf("活動數量 = %d，應為 %d: %+v", len(activities), len(want), activities)
+	}
+	for _, w := range want {
+		found := false
+		for _, activity := range activities {
+			if activity.Type == w.activityType && activity.StartTime().Equal(w.start) && activity.EndTime().Equal(w.end) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("找不到活動 %v %v - %v", w.activityType, w.start, w.end)
+		}
+	}
+
+	today, err := tracker.GetTodayActivities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(today) != 1 || today[0].Type != domain.MouseActivity {
+		t.Errorf("午夜後今天的活動 = %+v，應只有跨過午夜的滑鼠活動", today)
+	}
+
+	checkStats := func(name string) {
+		t.Helper()
+
+		stats := tracker.GetDailyStats()
+		wantStats := []domain.DailyStats{
+			{Date: at(24, 0), TotalDuration: 30 * time.Minute, MouseDuration: 30 * time.Minute},
+			{Date: day, TotalDuration: 180 * time.Minute, MouseDuration: 90 * time.Minute, KeyboardDuration: 120 * time.Minute},
+		}
+		if len(stats) != len(wantStats) {
+			t.Fatalf("%s: 每日統計 = %+v", name, stats)
+		}
+		for i, w := range wantStats {
+			got := stats[i]
+			if !got.Date.Equal(w.Date) || got.TotalDuration != w.TotalDuration ||
+				got.MouseDuration != w.MouseDuration || got.KeyboardDuration != w.KeyboardDuration {
+				t.Errorf("%s: %v 的統計 = 總計 %v 滑鼠 %v 鍵盤 %v，應為 %v %v %v", name, w.Date.Format(time.DateOnly),
+					got.TotalDuration, got.MouseDuration, got.KeyboardDuration,
+					w.TotalDuration, w.MouseDuration, w.KeyboardDuration)
+			}
+		}
+	}
+	checkStats("累計彙總")
+
+	if err := env.repo.RebuildRollups(ctx); err != nil {
+		t.Fatal(err)
+	}
+	tracker.InvalidateStats()
+	checkStats("重建彙總")
+
+	if err := tracker.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatal(err)
+	}
+}
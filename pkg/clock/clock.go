@@ -0,0 +1,57 @@
+package clock
+
+import "time"
+
+// Clock 抽象化目前時間與計時器，讓跨日、閾值等行為可以用假時鐘測試
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// Monotonic 回傳不含系統休眠時間的單調時間，與 Now 比較可以偵測休眠或調整時鐘造成的跳躍
+	Monotonic() time.Duration
+	NewTicker(d time.Duration) Ticker
+	After(d time.Duration) <-chan time.Time
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real 使用系統時間
+type Real struct{}
+
+// processStart 是 Real.Monotonic 的起點
+var processStart = time.Now()
+
+func New() Real {
+	return Real{}
+}
+
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+func (Real) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+// Monotonic 使用 Go 的單調時鐘，Linux 上系統休眠期間不會前進
+func (Real) Monotonic() time.Duration {
+	return time.Since(processStart)
+}
+
+func (Real) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
@@ -0,0 +1,179 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake 是只會在呼叫 Advance、Set 或 Suspend 時前進的時鐘
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	mono    time.Duration
+	tickers []*fakeTicker
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Monotonic() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.mono
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTicker{
+		clock:  f,
+		period: d,
+		next:   f.now.Add(d),
+		ch:     make(chan time.Time, 1),
+	}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	at := f.now.Add(d)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{at: at, ch: ch})
+	return ch
+}
+
+// Advance 讓時間前進 d，途中到期的計時器依時間順序觸發
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set 將時間設定為 t；若 t 早於目前時間，只會改變 Now 的結果
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for {
+		at, ok := f.nextDeadlineLocked()
+		if !ok || at.After(t) {
+			break
+		}
+		f.advanceLocked(at)
+		f.fireLocked(at)
+	}
+	f.advanceLocked(t)
+}
+
+// Suspend 模擬系統休眠 d：Now 前進 d，但單調時間不變，
+// 計時器與 time.Ticker 一樣以單調時間計算，延後 d 觸發
+func (f *Fake) Suspend(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = f.now.Add(d)
+	for _, t := range f.tickers {
+		t.next = t.next.Add(d)
+	}
+	for i := range f.waiters {
+		f.waiters[i].at = f.waiters[i].at.Add(d)
+	}
+}
+
+func (f *Fake) advanceLocked(t time.Time) {
+	if t.After(f.now) {
+		f.mono += t.Sub(f.now)
+	}
+	f.now = t
+}
+
+func (f *Fake) nextDeadlineLocked() (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, t := range f.tickers {
+		if !found || t.next.Before(next) {
+			next, found = t.next, true
+		}
+	}
+	for _, w := range f.waiters {
+		if !found || w.at.Before(next) {
+			next, found = w.at, true
+		}
+	}
+	return next, found
+}
+
+func (f *Fake) fireLocked(at time.Time) {
+	for _, t := range f.tickers {
+		if !t.next.After(at) {
+			// 與 time.Ticker 相同，接收端來不及讀取時丟棄
+			select {
+			case t.ch <- at:
+			default:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+
+	waiters := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.at.After(at) {
+			waiters = append(waiters, w)
+			continue
+		}
+		w.ch <- at
+	}
+	f.waiters = waiters
+}
+
+type fakeTicker struct {
+	clock  *Fake
+	period time.Duration
+	next   time.Time
+	ch     chan time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time {
+	return t.ch
+}
+
+func (t *fakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}